@@ -1,8 +1,16 @@
 package application
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/journal"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
 	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/log"
 	"github.com/diez37/go-packages/repeater"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/attribute"
@@ -14,28 +22,85 @@ import (
 
 const (
 	blockerInitCap = 5000
+
+	// blockerEventsSeparator separator of reason and events in record of journal
+	blockerEventsSeparator = 0
 )
 
+// Blocker queue of blocks waiting for apply, the events attached to ctx of BlockByUUID wait with the blocks
+// and they are written to outbox in the same transaction as the blocks
 type Blocker interface {
 	repeater.Process
 	repository.Blocker
+	repository.Archiver
+
+	// Restore returning to queue the blocks from journal
+	Restore(ctx context.Context) error
+
+	// IsBlocked checking that block of token is accepted but not yet applied to repository
+	IsBlocked(uuid uuid.UUID) bool
+
+	// Reason return reason of the last accepted but not yet applied block of token
+	Reason(uuid uuid.UUID) (repository.RevokeReason, bool)
+
+	// Pending return tokens which blocks are accepted but not yet applied to repository
+	Pending() []uuid.UUID
+
+	// Flush return channel which signals that queue reached size of flush
+	Flush() <-chan struct{}
+
+	// Size return number of blocks waiting for apply, including blocks in process
+	Size() int
+}
+
+// blockerBlock accepted block of token waiting for apply to repository
+type blockerBlock struct {
+	uuid   uuid.UUID
+	reason repository.RevokeReason
+	// events events of block, attached to the first block of call
+	events []*repository.Event
 }
 
 type blocker struct {
 	mutex *sync.Mutex
+	// processMutex not allows parallel runs of process by repeater and by flush
+	processMutex *sync.Mutex
 
 	repository repository.Repository
+	journal    journal.Journal
+
+	blocks []*blockerBlock
+	// pending number of not applied blocks by uuid, includes blocks in process
+	pending map[uuid.UUID]int
+	// reasons reason of the last not applied block by uuid
+	reasons map[uuid.UUID]repository.RevokeReason
+	// processing number of blocks taken from queue by running process
+	processing int
 
-	uuids  []uuid.UUID
-	tracer trace.Tracer
+	limiter *limiter
+	metrics *metrics.Metrics
+	tracer  trace.Tracer
 }
 
-func NewBlocker(repository repository.Repository, tracer trace.Tracer) Blocker {
+func NewBlocker(
+	tokenRepository repository.Repository,
+	journal journal.Journal,
+	config *config.Token,
+	logger log.Logger,
+	metrics *metrics.Metrics,
+	tracer trace.Tracer,
+) Blocker {
 	return &blocker{
-		mutex:      &sync.Mutex{},
-		repository: repository,
-		uuids:      make([]uuid.UUID, 0, blockerInitCap),
-		tracer:     tracer,
+		mutex:        &sync.Mutex{},
+		processMutex: &sync.Mutex{},
+		repository:   tokenRepository,
+		journal:      journal,
+		blocks:       make([]*blockerBlock, 0, blockerInitCap),
+		pending:      map[uuid.UUID]int{},
+		reasons:      map[uuid.UUID]repository.RevokeReason{},
+		limiter:      newLimiter("blocker", config.BlockerLimit, config, logger, metrics),
+		metrics:      metrics,
+		tracer:       tracer,
 	}
 }
 
@@ -43,38 +108,160 @@ func (service *blocker) Process(ctx context.Context) error {
 	ctx, span := service.tracer.Start(ctx, "service.blocker.process")
 	defer span.End()
 
-	var errs error
-	wg := &sync.WaitGroup{}
+	service.processMutex.Lock()
+	defer service.processMutex.Unlock()
 
 	service.mutex.Lock()
 
-	wg.Add(1)
-	go func(ctx context.Context, uuids ...uuid.UUID) {
-		wg.Done()
-		if err := service.repository.BlockByUUID(ctx, uuids...); err != nil {
-			errs = multierr.Append(errs, err)
+	blocks := service.blocks
+	service.blocks = make([]*blockerBlock, 0, blockerInitCap)
+	service.processing = len(blocks)
+
+	service.mutex.Unlock()
+
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	span.SetAttributes(attribute.Int("length", len(blocks)))
+
+	var err error
 
-			if err := service.BlockByUUID(ctx, uuids...); err != nil {
-				errs = multierr.Append(errs, err)
-			}
+	applied := 0
+	for applied < len(blocks) {
+		size := service.limiter.chunk(len(blocks) - applied)
+
+		if err = service.apply(ctx, blocks[applied:applied+size]...); err != nil {
+			break
 		}
-	}(ctx, service.uuids...)
 
-	service.uuids = make([]uuid.UUID, 0, blockerInitCap)
+		applied += size
+	}
+
+	service.metrics.BlockerPending.Sub(float64(applied))
 
-	service.mutex.Unlock()
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	for _, block := range blocks[:applied] {
+		if service.pending[block.uuid]--; service.pending[block.uuid] <= 0 {
+			delete(service.pending, block.uuid)
+			delete(service.reasons, block.uuid)
+		}
+	}
+
+	// the not applied blocks are still in journal, return them to the head of queue
+	service.blocks = append(blocks[applied:], service.blocks...)
+	service.processing = 0
+	service.limiter.release()
+
+	if err != nil && applied == 0 {
+		return err
+	}
+
+	records, recordsErr := blockerRecords(service.blocks...)
+	if recordsErr != nil {
+		return multierr.Append(err, recordsErr)
+	}
+
+	// the journal keeps only blocks which are not applied
+	return multierr.Append(err, service.journal.Rewrite(records...))
+}
+
+func (service *blocker) Flush() <-chan struct{} {
+	return service.limiter.flushes
+}
+
+func (service *blocker) Restore(ctx context.Context) error {
+	_, span := service.tracer.Start(ctx, "service.blocker.restore")
+	defer span.End()
+
+	var blocks []*blockerBlock
+
+	err := service.journal.Replay(func(record []byte) error {
+		if len(record) < len(uuid.UUID{}) {
+			return errors.New(fmt.Sprintf("blocker: journal record of %d bytes is too short", len(record)))
+		}
+
+		uuid, err := uuid.FromBytes(record[:len(uuid.UUID{})])
+		if err != nil {
+			return err
+		}
+
+		rest := record[len(uuid):]
+
+		index := bytes.IndexByte(rest, blockerEventsSeparator)
+		if index < 0 {
+			return errors.New(fmt.Sprintf("blocker: journal record of '%s' without separator of events", uuid.String()))
+		}
+
+		block := &blockerBlock{uuid: uuid, reason: repository.RevokeReason(rest[:index])}
+		if err := json.Unmarshal(rest[index+1:], &block.events); err != nil {
+			return err
+		}
+
+		blocks = append(blocks, block)
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int("length", len(blocks)))
+
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	service.add(blocks...)
+
+	return nil
+}
+
+func (service *blocker) IsBlocked(uuid uuid.UUID) bool {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	_, exist := service.pending[uuid]
 
-	wg.Wait()
+	return exist
+}
+
+func (service *blocker) Reason(uuid uuid.UUID) (repository.RevokeReason, bool) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	reason, exist := service.reasons[uuid]
+
+	return reason, exist
+}
+
+func (service *blocker) Size() int {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
 
-	return errs
+	return len(service.blocks) + service.processing
 }
 
-func (service *blocker) BlockByUUID(ctx context.Context, uuids ...uuid.UUID) error {
+func (service *blocker) Pending() []uuid.UUID {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+
+	uuids := make([]uuid.UUID, 0, len(service.pending))
+	for uuid := range service.pending {
+		uuids = append(uuids, uuid)
+	}
+
+	return uuids
+}
+
+func (service *blocker) BlockByUUID(ctx context.Context, reason repository.RevokeReason, uuids ...uuid.UUID) error {
 	ctx, span := service.tracer.Start(ctx, "blocker.uuid")
 	defer span.End()
 
 	span.SetAttributes(
 		attribute.Int("length", len(uuids)),
+		attribute.String("reason", string(reason)),
 		attribute.String("repository", "service"),
 		attribute.String("service", "blocker"),
 	)
@@ -82,7 +269,31 @@ func (service *blocker) BlockByUUID(ctx context.Context, uuids ...uuid.UUID) err
 	service.mutex.Lock()
 	defer service.mutex.Unlock()
 
-	service.uuids = append(service.uuids, uuids...)
+	err := service.limiter.acquire(ctx, service.mutex, func() int { return len(service.blocks) + service.processing }, len(uuids))
+	if err != nil {
+		return err
+	}
+
+	blocks := make([]*blockerBlock, 0, len(uuids))
+	for _, uuid := range uuids {
+		blocks = append(blocks, &blockerBlock{uuid: uuid, reason: reason})
+	}
+
+	if len(blocks) > 0 {
+		blocks[0].events = repository.EventsFromContext(ctx)
+	}
+
+	records, err := blockerRecords(blocks...)
+	if err != nil {
+		return err
+	}
+
+	if err := service.journal.Append(records...); err != nil {
+		return err
+	}
+
+	service.add(blocks...)
+	service.limiter.notify(len(service.blocks))
 
 	return nil
 }
@@ -98,3 +309,75 @@ func (service *blocker) BlockByDate(ctx context.Context, date time.Time) error {
 
 	return service.repository.BlockByDate(ctx, date)
 }
+
+func (service *blocker) Purge(ctx context.Context, date time.Time) error {
+	ctx, span := service.tracer.Start(ctx, "archiver.purge")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("repository", "service"),
+		attribute.String("service", "blocker"),
+	)
+
+	return service.repository.Purge(ctx, date)
+}
+
+// apply applying blocks to repository, one request with events of its blocks for every reason
+func (service *blocker) apply(ctx context.Context, blocks ...*blockerBlock) error {
+	var reasons []repository.RevokeReason
+	uuidsByReason := map[repository.RevokeReason][]uuid.UUID{}
+	eventsByReason := map[repository.RevokeReason][]*repository.Event{}
+
+	for _, block := range blocks {
+		if _, exist := uuidsByReason[block.reason]; !exist {
+			reasons = append(reasons, block.reason)
+		}
+
+		uuidsByReason[block.reason] = append(uuidsByReason[block.reason], block.uuid)
+		eventsByReason[block.reason] = append(eventsByReason[block.reason], block.events...)
+	}
+
+	for _, reason := range reasons {
+		reasonCtx := repository.WithEvents(ctx, eventsByReason[reason]...)
+
+		if err := service.repository.BlockByUUID(reasonCtx, reason, uuidsByReason[reason]...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// add putting blocks to queue, must be called under the lock
+func (service *blocker) add(blocks ...*blockerBlock) {
+	service.blocks = append(service.blocks, blocks...)
+
+	for _, block := range blocks {
+		service.pending[block.uuid]++
+		service.reasons[block.uuid] = block.reason
+	}
+
+	service.metrics.BlockerPending.Add(float64(len(blocks)))
+}
+
+// blockerRecords records of journal 'uuid + reason [+ separator + json of events]'
+func blockerRecords(blocks ...*blockerBlock) ([][]byte, error) {
+	records := make([][]byte, 0, len(blocks))
+
+	for _, block := range blocks {
+		events, err := json.Marshal(block.events)
+		if err != nil {
+			return nil, err
+		}
+
+		record := make([]byte, 0, len(block.uuid)+len(block.reason)+1+len(events))
+		record = append(record, block.uuid[:]...)
+		record = append(record, block.reason...)
+		record = append(record, blockerEventsSeparator)
+		record = append(record, events...)
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,148 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"github.com/Diez37/go-skeleton/domain"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/journal"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/google/uuid"
+	"path/filepath"
+	"testing"
+)
+
+// failingBlocks repository which fails blocks after number of successful blocks
+type failingBlocks struct {
+	repository.Repository
+	successes int
+}
+
+func (repository *failingBlocks) BlockByUUID(ctx context.Context, reason repository.RevokeReason, uuids ...uuid.UUID) error {
+	if repository.successes == 0 {
+		return errors.New("repository is down")
+	}
+
+	repository.successes--
+
+	return repository.Repository.BlockByUUID(ctx, reason, uuids...)
+}
+
+// newTestBlocker creating blocker over journal of dir and restoring it as after restart
+func newTestBlocker(t *testing.T, tokenRepository repository.Repository, tokenConfig *config.Token, dir string) Blocker {
+	t.Helper()
+
+	blockerJournal, err := journal.NewFile(filepath.Join(dir, "blocker.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = blockerJournal.Close()
+	})
+
+	blocker := NewBlocker(tokenRepository, blockerJournal, tokenConfig, testLogger(), testMetrics, testTracer)
+	if err := blocker.Restore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return blocker
+}
+
+// insertTestTokens writing new tokens to repository
+func insertTestTokens(t *testing.T, tokenRepository repository.Repository, count int) []*repository.RefreshToken {
+	t.Helper()
+
+	tokens := make([]*repository.RefreshToken, 0, count)
+	for index := 0; index < count; index++ {
+		tokens = append(tokens, newTestToken(uuid.New()))
+	}
+
+	if err := tokenRepository.Insert(context.Background(), tokens...); err != nil {
+		t.Fatal(err)
+	}
+
+	return tokens
+}
+
+func TestBlockerRestoresBlocksWithEvents(t *testing.T) {
+	dir := t.TempDir()
+	tokenRepository := repository.NewMemory(testTracer)
+	outbox := tokenRepository.(repository.Outbox)
+
+	token := insertTestTokens(t, tokenRepository, 1)[0]
+
+	ctx := withEvent(t, context.Background(), outbox, domain.EventSessionRevoked)
+	if err := newTestBlocker(t, tokenRepository, testTokenConfig(), dir).BlockByUUID(ctx, repository.RevokeReasonLogout, token.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := newTestBlocker(t, tokenRepository, testTokenConfig(), dir)
+
+	if reason, exist := restored.Reason(token.UUID); !exist || reason != repository.RevokeReasonLogout {
+		t.Fatalf("restored block of reason '%s', exist %t, want '%s'", reason, exist, repository.RevokeReasonLogout)
+	}
+
+	if err := restored.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tokenRepository.FindRevokedByUUID(context.Background(), token.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	if events := pending(t, outbox); len(events) != 1 || events[0].Type != domain.EventSessionRevoked {
+		t.Fatalf("events in outbox %v, want restored '%s'", events, domain.EventSessionRevoked)
+	}
+
+	// the journal keeps only not applied blocks
+	if size := newTestBlocker(t, tokenRepository, testTokenConfig(), dir).Size(); size != 0 {
+		t.Fatalf("%d blocks are restored after apply, want 0", size)
+	}
+}
+
+func TestBlockerRewritesJournalAfterPartialApply(t *testing.T) {
+	dir := t.TempDir()
+	tokenRepository := repository.NewMemory(testTracer)
+
+	tokenConfig := testTokenConfig()
+	tokenConfig.BufferChunk = 1
+
+	tokens := insertTestTokens(t, tokenRepository, 3)
+
+	blocker := newTestBlocker(t, &failingBlocks{Repository: tokenRepository, successes: 1}, tokenConfig, dir)
+	for _, token := range tokens {
+		if err := blocker.BlockByUUID(context.Background(), repository.RevokeReasonViolation, token.UUID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := blocker.Process(context.Background()); err == nil {
+		t.Fatal("expected error of failed chunk")
+	}
+
+	if blocker.IsBlocked(tokens[0].UUID) {
+		t.Fatalf("applied block of '%s' is pending", tokens[0].UUID)
+	}
+
+	restored := newTestBlocker(t, tokenRepository, tokenConfig, dir)
+	if size := restored.Size(); size != 2 {
+		t.Fatalf("%d blocks are restored, want 2 not applied", size)
+	}
+
+	for _, token := range tokens[1:] {
+		if reason, exist := restored.Reason(token.UUID); !exist || reason != repository.RevokeReasonViolation {
+			t.Fatalf("block of '%s' is not restored", token.UUID)
+		}
+	}
+
+	if err := restored.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range tokens {
+		if _, err := tokenRepository.FindRevokedByUUID(context.Background(), token.UUID); err != nil {
+			t.Fatalf("token '%s' is not revoked: %s", token.UUID, err)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package application
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// Cache write-behind layer over saver and blocker, reads reflect the inserts and blocks
+// which are accepted but not yet written to repository. The events of context wait in buffers
+// with their tokens and blocks and they are written in the same transaction
+type Cache interface {
+	repository.Repository
+
+	// Restore restoring saver and blocker from their journals, the restored blocks are applied to restored tokens
+	Restore(ctx context.Context) error
+}
+
+type cache struct {
+	saver   Saver
+	blocker Blocker
+	outbox  repository.Outbox
+	tracer  trace.Tracer
+}
+
+func NewCache(saver Saver, blocker Blocker, outbox repository.Outbox, tracer trace.Tracer) Cache {
+	return &cache{saver: saver, blocker: blocker, outbox: outbox, tracer: tracer}
+}
+
+func (service *cache) Restore(ctx context.Context) error {
+	ctx, span := service.tracer.Start(ctx, "service.cache.restore")
+	defer span.End()
+
+	if err := service.blocker.Restore(ctx); err != nil {
+		return err
+	}
+
+	if err := service.saver.Restore(ctx); err != nil {
+		return err
+	}
+
+	// the restored blocks had no place for events of the canceled tokens, they are pushed directly
+	events, err := service.saver.Cancel(ctx, service.blocker.Pending()...)
+	if err != nil {
+		return err
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	return service.outbox.Push(ctx, events...)
+}
+
+func (service *cache) FindByLogin(ctx context.Context, login uuid.UUID) ([]*repository.RefreshToken, error) {
+	ctx, span := service.tracer.Start(ctx, "finder.login")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("login", login.String()),
+		attribute.String("repository", "service"),
+		attribute.String("service", "cache"),
+	)
+
+	tokens, err := service.saver.FindByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	actualTokens := make([]*repository.RefreshToken, 0, len(tokens))
+	for _, token := range tokens {
+		if !service.blocker.IsBlocked(token.UUID) {
+			actualTokens = append(actualTokens, token)
+		}
+	}
+
+	if len(actualTokens) == 0 {
+		return nil, db.RecordNotFoundError
+	}
+
+	return actualTokens, nil
+}
+
+// FindAllByLogin finding tokens of login, the token of not yet applied block has reason of block without time of revoke.
+// The token canceled in buffer of saver is never written and isn't found
+func (service *cache) FindAllByLogin(ctx context.Context, login uuid.UUID) ([]*repository.RefreshToken, error) {
+	ctx, span := service.tracer.Start(ctx, "finder.all.login")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("login", login.String()),
+		attribute.String("repository", "service"),
+		attribute.String("service", "cache"),
+	)
+
+	tokens, err := service.saver.FindAllByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	allTokens := make([]*repository.RefreshToken, 0, len(tokens))
+	for _, token := range tokens {
+		if reason, exist := service.blocker.Reason(token.UUID); exist && token.RevokedAt == nil {
+			revokedToken := *token
+			revokedToken.RevokeReason = reason
+			token = &revokedToken
+		}
+
+		allTokens = append(allTokens, token)
+	}
+
+	return allTokens, nil
+}
+
+func (service *cache) FindByUUID(ctx context.Context, uuid uuid.UUID) (*repository.RefreshToken, error) {
+	ctx, span := service.tracer.Start(ctx, "finder.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", "service"),
+		attribute.String("service", "cache"),
+	)
+
+	if service.blocker.IsBlocked(uuid) {
+		return nil, db.RecordNotFoundError
+	}
+
+	return service.saver.FindByUUID(ctx, uuid)
+}
+
+// FindRevokedByUUID finding revoked token, the token of not yet applied block has reason of block without time of revoke
+func (service *cache) FindRevokedByUUID(ctx context.Context, uuid uuid.UUID) (*repository.RefreshToken, error) {
+	ctx, span := service.tracer.Start(ctx, "finder.revoked.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", "service"),
+		attribute.String("service", "cache"),
+	)
+
+	reason, exist := service.blocker.Reason(uuid)
+	if !exist {
+		return service.saver.FindRevokedByUUID(ctx, uuid)
+	}
+
+	token, err := service.saver.FindByUUID(ctx, uuid)
+	if err == db.RecordNotFoundError {
+		// the token was canceled in buffer of saver and never written
+		return &repository.RefreshToken{UUID: uuid, RevokeReason: reason}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	revokedToken := *token
+	revokedToken.RevokeReason = reason
+
+	return &revokedToken, nil
+}
+
+func (service *cache) Insert(ctx context.Context, tokens ...*repository.RefreshToken) error {
+	return service.saver.Insert(ctx, tokens...)
+}
+
+// BlockByUUID removing the blocked tokens from buffer of saver and queueing the blocks.
+// Cancel waits for a running save, so that a token is not written to repository after its block is applied.
+// The events of canceled tokens are written with the block
+func (service *cache) BlockByUUID(ctx context.Context, reason repository.RevokeReason, uuids ...uuid.UUID) error {
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	events, err := service.saver.Cancel(ctx, uuids...)
+	if err != nil {
+		return err
+	}
+
+	return service.blocker.BlockByUUID(repository.WithEvents(ctx, events...), reason, uuids...)
+}
+
+func (service *cache) BlockByDate(ctx context.Context, date time.Time) error {
+	return service.blocker.BlockByDate(ctx, date)
+}
+
+func (service *cache) Purge(ctx context.Context, date time.Time) error {
+	return service.blocker.Purge(ctx, date)
+}
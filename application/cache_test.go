@@ -0,0 +1,285 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"github.com/Diez37/go-skeleton/domain"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+)
+
+// withEvent attaching outbox record of event of type for destination 'test' to ctx
+func withEvent(t *testing.T, ctx context.Context, outbox repository.Outbox, eventType string) context.Context {
+	t.Helper()
+
+	ctx, err := NewEvents(outbox, []string{"test"}, testTracer).Attach(ctx, &domain.Event{Type: eventType})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ctx
+}
+
+func TestCacheWritesEventsWithFlush(t *testing.T) {
+	tokenRepository := repository.NewMemory(testTracer)
+	outbox := tokenRepository.(repository.Outbox)
+	writeBehind := newTestWriteBehind(t, tokenRepository, testTokenConfig(), "")
+
+	token := newTestToken(uuid.New())
+	if err := writeBehind.cache.Insert(withEvent(t, context.Background(), outbox, domain.EventSessionCreated), token); err != nil {
+		t.Fatal(err)
+	}
+
+	// the event waits with the token, it is not delivered before the token is saved
+	if events := pending(t, outbox); len(events) != 0 {
+		t.Fatalf("%d events in outbox before flush", len(events))
+	}
+
+	writeBehind.flush(t)
+
+	events := pending(t, outbox)
+	if len(events) != 1 || events[0].Type != domain.EventSessionCreated {
+		t.Fatalf("events in outbox %v, want '%s'", events, domain.EventSessionCreated)
+	}
+}
+
+func TestCacheMovesEventsOfCanceledTokenToBlock(t *testing.T) {
+	tokenRepository := repository.NewMemory(testTracer)
+	outbox := tokenRepository.(repository.Outbox)
+	writeBehind := newTestWriteBehind(t, tokenRepository, testTokenConfig(), "")
+
+	token := newTestToken(uuid.New())
+	if err := writeBehind.cache.Insert(withEvent(t, context.Background(), outbox, domain.EventSessionCreated), token); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := withEvent(t, context.Background(), outbox, domain.EventSessionRevoked)
+	if err := writeBehind.cache.BlockByUUID(ctx, repository.RevokeReasonLogout, token.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeBehind.saver.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if events := pending(t, outbox); len(events) != 0 {
+		t.Fatalf("%d events in outbox before block is applied", len(events))
+	}
+
+	if err := writeBehind.blocker.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the canceled token is never saved, its event is written with the block
+	types := map[string]bool{}
+	for _, event := range pending(t, outbox) {
+		types[event.Type] = true
+	}
+
+	if len(types) != 2 || !types[domain.EventSessionCreated] || !types[domain.EventSessionRevoked] {
+		t.Fatalf("types of events in outbox %v, want created and revoked", types)
+	}
+}
+
+func TestCacheReadsOwnWrites(t *testing.T) {
+	ctx := context.Background()
+	tokenRepository := repository.NewMemory(testTracer)
+	writeBehind := newTestWriteBehind(t, tokenRepository, testTokenConfig(), "")
+
+	login := uuid.New()
+	saved, buffered := newTestToken(login), newTestToken(login)
+
+	if err := writeBehind.cache.Insert(ctx, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	writeBehind.flush(t)
+
+	// the token in buffer of saver is found before it is written
+	if err := writeBehind.cache.Insert(ctx, buffered); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tokenRepository.FindByUUID(ctx, buffered.UUID); err != db.RecordNotFoundError {
+		t.Fatalf("buffered token in repository before flush, error %v", err)
+	}
+
+	for _, token := range []*repository.RefreshToken{saved, buffered} {
+		if _, err := writeBehind.cache.FindByUUID(ctx, token.UUID); err != nil {
+			t.Fatalf("token '%s' is not found: %s", token.UUID, err)
+		}
+	}
+
+	tokens, err := writeBehind.cache.FindByLogin(ctx, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 2 {
+		t.Fatalf("%d tokens of login, want saved and buffered", len(tokens))
+	}
+}
+
+func TestCacheHidesPendingRevocations(t *testing.T) {
+	ctx := context.Background()
+	tokenRepository := repository.NewMemory(testTracer)
+	writeBehind := newTestWriteBehind(t, tokenRepository, testTokenConfig(), "")
+
+	login := uuid.New()
+	saved, buffered, kept := newTestToken(login), newTestToken(login), newTestToken(login)
+
+	if err := writeBehind.cache.Insert(ctx, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	writeBehind.flush(t)
+
+	if err := writeBehind.cache.Insert(ctx, buffered, kept); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeBehind.cache.BlockByUUID(ctx, repository.RevokeReasonLogout, saved.UUID, buffered.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the revocation is visible before blocker applies it
+	assertRevocations := func(revokedAt bool) {
+		t.Helper()
+
+		for _, token := range []*repository.RefreshToken{saved, buffered} {
+			if _, err := writeBehind.cache.FindByUUID(ctx, token.UUID); err != db.RecordNotFoundError {
+				t.Fatalf("revoked token '%s' is found, error %v", token.UUID, err)
+			}
+		}
+
+		tokens, err := writeBehind.cache.FindByLogin(ctx, login)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(tokens) != 1 || tokens[0].UUID != kept.UUID {
+			t.Fatalf("tokens of login %v, want only '%s'", tokens, kept.UUID)
+		}
+
+		revoked, err := writeBehind.cache.FindRevokedByUUID(ctx, saved.UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if revoked.RevokeReason != repository.RevokeReasonLogout || (revoked.RevokedAt != nil) != revokedAt {
+			t.Fatalf("revoked token %+v, want reason '%s'", revoked, repository.RevokeReasonLogout)
+		}
+	}
+
+	assertRevocations(false)
+
+	writeBehind.flush(t)
+
+	assertRevocations(true)
+
+	// the canceled token is never written
+	if _, err := tokenRepository.FindRevokedByUUID(ctx, buffered.UUID); err != db.RecordNotFoundError {
+		t.Fatalf("canceled token is written, error %v", err)
+	}
+}
+
+func TestCacheConcurrentWritesAndFlushes(t *testing.T) {
+	ctx := context.Background()
+	tokenRepository := repository.NewMemory(testTracer)
+	writeBehind := newTestWriteBehind(t, tokenRepository, testTokenConfig(), "")
+
+	done := make(chan struct{})
+	flushed := make(chan error, 1)
+
+	go func() {
+		defer close(flushed)
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			if err := writeBehind.saver.Process(ctx); err != nil {
+				flushed <- err
+				return
+			}
+
+			if err := writeBehind.blocker.Process(ctx); err != nil {
+				flushed <- err
+				return
+			}
+		}
+	}()
+
+	var group sync.WaitGroup
+	var mutex sync.Mutex
+	var uuids []uuid.UUID
+
+	errs := make(chan error, 8)
+
+	for worker := 0; worker < 8; worker++ {
+		group.Add(1)
+
+		go func() {
+			defer group.Done()
+
+			for index := 0; index < 100; index++ {
+				token := newTestToken(uuid.New())
+
+				if err := writeBehind.cache.Insert(ctx, token); err != nil {
+					errs <- err
+					return
+				}
+
+				mutex.Lock()
+				uuids = append(uuids, token.UUID)
+				mutex.Unlock()
+
+				if _, err := writeBehind.cache.FindByUUID(ctx, token.UUID); err != nil {
+					errs <- fmt.Errorf("inserted token '%s' is not found: %w", token.UUID, err)
+					return
+				}
+
+				if err := writeBehind.cache.BlockByUUID(ctx, repository.RevokeReasonLogout, token.UUID); err != nil {
+					errs <- err
+					return
+				}
+
+				if _, err := writeBehind.cache.FindByUUID(ctx, token.UUID); err != db.RecordNotFoundError {
+					errs <- fmt.Errorf("revoked token '%s' is found, error %v", token.UUID, err)
+					return
+				}
+			}
+		}()
+	}
+
+	group.Wait()
+	close(done)
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if err := <-flushed; err != nil {
+		t.Fatal(err)
+	}
+
+	writeBehind.flush(t)
+
+	// every revocation is applied and no revoked token is written as actual
+	if size := writeBehind.blocker.Size(); size != 0 {
+		t.Fatalf("%d blocks are pending after flush", size)
+	}
+
+	for _, uuid := range uuids {
+		if _, err := tokenRepository.FindByUUID(ctx, uuid); err != db.RecordNotFoundError {
+			t.Fatalf("revoked token '%s' is actual in repository, error %v", uuid, err)
+		}
+	}
+}
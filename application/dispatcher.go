@@ -0,0 +1,105 @@
+package application
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/log"
+	"github.com/diez37/go-packages/repeater"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
+	"time"
+)
+
+type Dispatcher interface {
+	repeater.Process
+}
+
+type dispatcher struct {
+	outbox     repository.Outbox
+	publishers map[string]EventPublisher
+	config     *config.Events
+	logger     log.Logger
+	tracer     trace.Tracer
+}
+
+// NewDispatcher creating process which delivers events of outbox to publishers by destination,
+// the failed delivery is retried with exponential backoff until attempts are over
+func NewDispatcher(
+	outbox repository.Outbox,
+	publishers map[string]EventPublisher,
+	config *config.Events,
+	logger log.Logger,
+	tracer trace.Tracer,
+) Dispatcher {
+	return &dispatcher{outbox: outbox, publishers: publishers, config: config, logger: logger, tracer: tracer}
+}
+
+func (service *dispatcher) Process(ctx context.Context) error {
+	ctx, span := service.tracer.Start(ctx, "service.dispatcher.process")
+	defer span.End()
+
+	now := time.Now().In(time.UTC)
+
+	events, err := service.outbox.Fetch(ctx, now, service.config.Batch)
+	if err != nil {
+		return err
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	span.SetAttributes(attribute.Int("length", len(events)))
+
+	var errs error
+	done := make([]uuid.UUID, 0, len(events))
+
+	for _, event := range events {
+		publisher, exist := service.publishers[event.Destination]
+		if !exist {
+			service.logger.Warnf("dispatcher: destination '%s' of event '%s' unknown, event dropped", event.Destination, event.UUID)
+			done = append(done, event.UUID)
+			continue
+		}
+
+		err := publisher.Publish(ctx, event.UUID, event.Type, event.Payload)
+		if err == nil {
+			done = append(done, event.UUID)
+			continue
+		}
+
+		if event.Attempts+1 >= service.config.Attempts {
+			service.logger.Errorf("dispatcher: event '%s' to '%s' dropped after %d attempts - %s", event.UUID, event.Destination, event.Attempts+1, err)
+			done = append(done, event.UUID)
+			continue
+		}
+
+		service.logger.Warnf("dispatcher: event '%s' to '%s' failed - %s", event.UUID, event.Destination, err)
+
+		// the event which retry failed stays in outbox and it is delivered again by the next process
+		if err := service.outbox.Retry(ctx, event.UUID, now.Add(service.backoff(event.Attempts))); err != nil {
+			errs = multierr.Append(errs, err)
+		}
+	}
+
+	// the delivered events are removed even after failed retries, so that they are not delivered twice
+	return multierr.Append(errs, service.outbox.Remove(ctx, done...))
+}
+
+// backoff return delay before retry after attempts, it doubles from initial up to maximum
+func (service *dispatcher) backoff(attempts uint) time.Duration {
+	backoff := service.config.BackoffInitial
+
+	for ; attempts > 0 && backoff < service.config.BackoffMaximum; attempts-- {
+		backoff *= 2
+	}
+
+	if backoff > service.config.BackoffMaximum {
+		return service.config.BackoffMaximum
+	}
+
+	return backoff
+}
@@ -0,0 +1,185 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"github.com/Diez37/go-skeleton/domain"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+func testEventsConfig() *config.Events {
+	return &config.Events{
+		Batch:          config.EventsBatchDefault,
+		Attempts:       3,
+		BackoffInitial: time.Second,
+		BackoffMaximum: 3 * time.Second,
+	}
+}
+
+// pushEvents emitting events of types to destinations
+func pushEvents(t *testing.T, outbox repository.Outbox, destinations []string, types ...string) {
+	t.Helper()
+
+	events := make([]*domain.Event, 0, len(types))
+	for _, eventType := range types {
+		events = append(events, &domain.Event{Type: eventType, Login: uuid.New(), Session: uuid.New()})
+	}
+
+	if err := NewEvents(outbox, destinations, testTracer).Emit(context.Background(), events...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pending return events of outbox including postponed ones
+func pending(t *testing.T, outbox repository.Outbox) []*repository.Event {
+	t.Helper()
+
+	events, err := outbox.Fetch(context.Background(), time.Now().Add(time.Hour), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return events
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	outbox := repository.NewMemoryOutbox()
+	webhook, broker := newTestPublisher(), newTestPublisher()
+
+	pushEvents(t, outbox, []string{"webhook", "broker"}, domain.EventSessionCreated, domain.EventSessionRevoked)
+
+	dispatcher := NewDispatcher(outbox, map[string]EventPublisher{"webhook": webhook, "broker": broker}, testEventsConfig(), testLogger(), testTracer)
+	if err := dispatcher.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, publisher := range map[string]*testPublisher{"webhook": webhook, "broker": broker} {
+		if messages := publisher.published(); len(messages) != 2 {
+			t.Fatalf("%s received %d messages, want 2", name, len(messages))
+		}
+	}
+
+	if events := pending(t, outbox); len(events) != 0 {
+		t.Fatalf("%d events are left in outbox", len(events))
+	}
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	outbox := repository.NewMemoryOutbox()
+	publisher := newTestPublisher()
+	publisher.fail(errors.New("unavailable"))
+
+	pushEvents(t, outbox, []string{"webhook"}, domain.EventSessionCreated)
+
+	eventsConfig := testEventsConfig()
+	dispatcher := NewDispatcher(outbox, map[string]EventPublisher{"webhook": publisher}, eventsConfig, testLogger(), testTracer)
+
+	before := time.Now()
+	if err := dispatcher.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	events := pending(t, outbox)
+	if len(events) != 1 {
+		t.Fatalf("%d events in outbox, want 1", len(events))
+	}
+
+	if events[0].Attempts != 1 || events[0].SendAt.Before(before.Add(eventsConfig.BackoffInitial)) {
+		t.Fatalf("event has %d attempts and send at %s, want 1 attempt after backoff", events[0].Attempts, events[0].SendAt)
+	}
+
+	// the postponed event is not delivered before its time
+	publisher.fail(nil)
+
+	if err := dispatcher.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if messages := publisher.published(); len(messages) != 0 {
+		t.Fatalf("%d messages delivered before backoff", len(messages))
+	}
+}
+
+func TestDispatcherDropsAfterAttempts(t *testing.T) {
+	outbox := repository.NewMemoryOutbox()
+	publisher := newTestPublisher()
+	publisher.fail(errors.New("unavailable"))
+
+	pushEvents(t, outbox, []string{"webhook", "unknown"}, domain.EventSessionCreated)
+
+	eventsConfig := testEventsConfig()
+	dispatcher := NewDispatcher(outbox, map[string]EventPublisher{"webhook": publisher}, eventsConfig, testLogger(), testTracer)
+
+	for attempt := uint(1); attempt <= eventsConfig.Attempts; attempt++ {
+		events, err := outbox.Fetch(context.Background(), time.Now().Add(time.Hour), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the postponed events are made due
+		for _, event := range events {
+			if err := outbox.Remove(context.Background(), event.UUID); err != nil {
+				t.Fatal(err)
+			}
+
+			event.SendAt = time.Now().Add(-time.Second)
+			if err := outbox.Push(context.Background(), event); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := dispatcher.Process(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		// the event of unknown destination is dropped on the first attempt
+		if events := pending(t, outbox); attempt < eventsConfig.Attempts && len(events) != 1 {
+			t.Fatalf("%d events in outbox after attempt %d, want 1", len(events), attempt)
+		}
+	}
+
+	if events := pending(t, outbox); len(events) != 0 {
+		t.Fatalf("%d events in outbox after %d attempts", len(events), eventsConfig.Attempts)
+	}
+}
+
+// retryFailingOutbox outbox which retry fails
+type retryFailingOutbox struct {
+	repository.Outbox
+}
+
+func (outbox *retryFailingOutbox) Retry(_ context.Context, _ uuid.UUID, _ time.Time) error {
+	return errors.New("retry failed")
+}
+
+func TestDispatcherRemovesDeliveredOnRetryError(t *testing.T) {
+	outbox := &retryFailingOutbox{Outbox: repository.NewMemoryOutbox()}
+	delivered, failed := newTestPublisher(), newTestPublisher()
+	failed.fail(errors.New("unavailable"))
+
+	pushEvents(t, outbox, []string{"failed", "delivered"}, domain.EventSessionCreated)
+
+	dispatcher := NewDispatcher(outbox, map[string]EventPublisher{"failed": failed, "delivered": delivered}, testEventsConfig(), testLogger(), testTracer)
+	if err := dispatcher.Process(context.Background()); err == nil {
+		t.Fatal("error of retry is lost")
+	}
+
+	events := pending(t, outbox)
+	if len(events) != 1 || events[0].Destination != "failed" {
+		t.Fatalf("events in outbox %v, want only event of failed destination", events)
+	}
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	service := &dispatcher{config: &config.Events{BackoffInitial: time.Second, BackoffMaximum: 5 * time.Second}}
+
+	for attempts, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if backoff := service.backoff(uint(attempts)); backoff != want {
+			t.Fatalf("backoff after %d attempts %s, want %s", attempts, backoff, want)
+		}
+	}
+}
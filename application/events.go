@@ -0,0 +1,97 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/Diez37/go-skeleton/domain"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+type Events interface {
+	// Emit adding events to outbox, one record for every destination
+	Emit(ctx context.Context, events ...*domain.Event) error
+
+	// Attach adding outbox records of events to context, the repository stores them in one transaction with write of tokens
+	Attach(ctx context.Context, events ...*domain.Event) (context.Context, error)
+}
+
+type events struct {
+	outbox       repository.Outbox
+	destinations []string
+	tracer       trace.Tracer
+}
+
+// NewEvents creating Events, without destinations events are not stored
+func NewEvents(outbox repository.Outbox, destinations []string, tracer trace.Tracer) Events {
+	return &events{outbox: outbox, destinations: destinations, tracer: tracer}
+}
+
+func (service *events) Emit(ctx context.Context, events ...*domain.Event) error {
+	if len(service.destinations) == 0 || len(events) == 0 {
+		return nil
+	}
+
+	ctx, span := service.tracer.Start(ctx, "service.events.emit")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("length", len(events)))
+
+	records, err := service.records(events...)
+	if err != nil {
+		return err
+	}
+
+	return service.outbox.Push(ctx, records...)
+}
+
+func (service *events) Attach(ctx context.Context, events ...*domain.Event) (context.Context, error) {
+	if len(service.destinations) == 0 || len(events) == 0 {
+		return ctx, nil
+	}
+
+	records, err := service.records(events...)
+	if err != nil {
+		return ctx, err
+	}
+
+	return repository.WithEvents(ctx, records...), nil
+}
+
+// records creating outbox records of events, one record for every destination
+func (service *events) records(events ...*domain.Event) ([]*repository.Event, error) {
+	now := time.Now().In(time.UTC)
+
+	records := make([]*repository.Event, 0, len(events)*len(service.destinations))
+
+	for _, event := range events {
+		if event.UUID == uuid.Nil {
+			event.UUID = uuid.New()
+		}
+
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = now
+		}
+
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, destination := range service.destinations {
+			records = append(records, &repository.Event{
+				UUID:        uuid.New(),
+				Destination: destination,
+				Type:        event.Type,
+				Payload:     payload,
+				CreatedAt:   event.CreatedAt,
+				SendAt:      now,
+			})
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,90 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	NotReadyError = errors.New("service is not ready")
+)
+
+// Check checking one dependency of service, an error means that service is not ready
+type Check func(ctx context.Context) error
+
+type Health interface {
+	// AddCheck adding check of readiness by name
+	AddCheck(name string, check Check) Health
+
+	// Ready return error of the first failed check, service is not ready before Start and after Stop
+	Ready(ctx context.Context) error
+
+	// Start marking service as ready to accept requests
+	Start()
+
+	// Stop marking service as not ready, it is called first on shutdown
+	Stop()
+}
+
+type healthCheck struct {
+	name  string
+	check Check
+}
+
+type health struct {
+	rwMutex *sync.RWMutex
+
+	checks []*healthCheck
+	// ready 1 between Start and Stop
+	ready uint32
+
+	tracer trace.Tracer
+}
+
+func NewHealth(tracer trace.Tracer) Health {
+	return &health{rwMutex: &sync.RWMutex{}, tracer: tracer}
+}
+
+func (service *health) AddCheck(name string, check Check) Health {
+	service.rwMutex.Lock()
+	defer service.rwMutex.Unlock()
+
+	service.checks = append(service.checks, &healthCheck{name: name, check: check})
+
+	return service
+}
+
+func (service *health) Ready(ctx context.Context) error {
+	ctx, span := service.tracer.Start(ctx, "service.health.ready")
+	defer span.End()
+
+	if atomic.LoadUint32(&service.ready) == 0 {
+		return NotReadyError
+	}
+
+	service.rwMutex.RLock()
+	defer service.rwMutex.RUnlock()
+
+	for _, healthCheck := range service.checks {
+		if err := healthCheck.check(ctx); err != nil {
+			span.SetAttributes(attribute.String("check", healthCheck.name))
+
+			return errors.New(fmt.Sprintf("%s: %s", healthCheck.name, err))
+		}
+	}
+
+	return nil
+}
+
+func (service *health) Start() {
+	atomic.StoreUint32(&service.ready, 1)
+}
+
+func (service *health) Stop() {
+	atomic.StoreUint32(&service.ready, 0)
+}
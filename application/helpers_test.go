@@ -0,0 +1,171 @@
+package application
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/journal"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/app"
+	"github.com/diez37/go-packages/log"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testMetrics metrics are registered once for all tests of package
+var testMetrics = metrics.NewMetrics(&app.Config{Name: "test"})
+
+var testTracer = trace.NewNoopTracerProvider().Tracer("")
+
+func testLogger() log.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return logger
+}
+
+// testTokenConfig defaults of tokens without flush by size and chunks
+func testTokenConfig() *config.Token {
+	return &config.Token{
+		MaximumTokens:      config.TokensMaximumTokensDefault,
+		AccessLifetime:     config.TokensAccessLifetimeDefault,
+		RefreshLifetime:    config.TokensRefreshLifetimeDefault,
+		AccessViolation:    config.TokensAccessViolationActionDefault,
+		RefreshCheckFields: config.TokensCheckFieldsForRefresh,
+		PeersTTL:           config.TokensPeersTTLDefault,
+		SaverLimit:         config.TokensSaverLimitDefault,
+		BlockerLimit:       config.TokensBlockerLimitDefault,
+		BufferOverflow:     config.TokensBufferOverflowDefault,
+	}
+}
+
+// testWriteBehind saver, blocker and cache over repository with journals in files of dir, empty dir disabled journals
+type testWriteBehind struct {
+	saver   Saver
+	blocker Blocker
+	cache   Cache
+}
+
+func newTestWriteBehind(t *testing.T, tokenRepository repository.Repository, tokenConfig *config.Token, dir string) *testWriteBehind {
+	t.Helper()
+
+	saverJournal, blockerJournal := journal.NewNop(), journal.NewNop()
+
+	if dir != "" {
+		var err error
+
+		if saverJournal, err = journal.NewFile(filepath.Join(dir, "saver.journal")); err != nil {
+			t.Fatal(err)
+		}
+
+		if blockerJournal, err = journal.NewFile(filepath.Join(dir, "blocker.journal")); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() {
+			_ = saverJournal.Close()
+			_ = blockerJournal.Close()
+		})
+	}
+
+	saver := NewSaver(tokenRepository, saverJournal, tokenConfig, testLogger(), testMetrics, testTracer)
+	blocker := NewBlocker(tokenRepository, blockerJournal, tokenConfig, testLogger(), testMetrics, testTracer)
+
+	return &testWriteBehind{
+		saver:   saver,
+		blocker: blocker,
+		cache:   NewCache(saver, blocker, tokenRepository.(repository.Outbox), testTracer),
+	}
+}
+
+// flush writing buffers of saver and blocker to repository
+func (writeBehind *testWriteBehind) flush(t *testing.T) {
+	t.Helper()
+
+	if err := writeBehind.saver.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeBehind.blocker.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestToken(login uuid.UUID) *repository.RefreshToken {
+	now := time.Now().In(time.UTC)
+
+	return &repository.RefreshToken{
+		UUID:        uuid.New(),
+		Login:       login,
+		Ip:          "127.0.0.1",
+		Fingerprint: "fingerprint",
+		UserAgent:   "test",
+		CreatedAt:   now,
+		ExpiresIn:   now.Add(time.Hour),
+	}
+}
+
+// eventually waiting for condition during second
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+
+	t.Fatal("condition is not reached in second")
+}
+
+// testMessage event received by testPublisher
+type testMessage struct {
+	delivery  uuid.UUID
+	eventType string
+	payload   []byte
+}
+
+// testPublisher stand-in of sink of events, it keeps published messages and fails while err is set
+type testPublisher struct {
+	mutex *sync.Mutex
+
+	messages []*testMessage
+	err      error
+}
+
+func newTestPublisher() *testPublisher {
+	return &testPublisher{mutex: &sync.Mutex{}}
+}
+
+func (publisher *testPublisher) Publish(_ context.Context, delivery uuid.UUID, eventType string, payload []byte) error {
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+
+	if publisher.err != nil {
+		return publisher.err
+	}
+
+	publisher.messages = append(publisher.messages, &testMessage{delivery: delivery, eventType: eventType, payload: payload})
+
+	return nil
+}
+
+func (publisher *testPublisher) fail(err error) {
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+
+	publisher.err = err
+}
+
+func (publisher *testPublisher) published() []*testMessage {
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+
+	return append([]*testMessage{}, publisher.messages...)
+}
@@ -0,0 +1,80 @@
+package application
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/log"
+	"github.com/diez37/go-packages/repeater"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sync/atomic"
+	"time"
+)
+
+type leader struct {
+	name   string
+	holder uuid.UUID
+	ttl    time.Duration
+
+	lease   repository.Lease
+	process repeater.Process
+
+	// isLeader 1 while this replica holds lease, used only for logging of changes
+	isLeader uint32
+
+	logger log.Logger
+	tracer trace.Tracer
+}
+
+// NewLeader creating repeater.Process which runs process only on replica holding lease of name.
+// The holder extends lease on every run, other replicas take over when lease expires, so ttl must be longer than delay of process
+func NewLeader(
+	name string,
+	ttl time.Duration,
+	process repeater.Process,
+	lease repository.Lease,
+	logger log.Logger,
+	tracer trace.Tracer,
+) repeater.Process {
+	return &leader{
+		name:    name,
+		holder:  uuid.New(),
+		ttl:     ttl,
+		lease:   lease,
+		process: process,
+		logger:  logger,
+		tracer:  tracer,
+	}
+}
+
+func (service *leader) Process(ctx context.Context) error {
+	ctx, span := service.tracer.Start(ctx, "service.leader.process")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("name", service.name),
+		attribute.String("holder", service.holder.String()),
+	)
+
+	acquired, err := service.lease.Acquire(ctx, service.name, service.holder, service.ttl)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(attribute.Bool("leader", acquired))
+
+	if !acquired {
+		if atomic.CompareAndSwapUint32(&service.isLeader, 1, 0) {
+			service.logger.Infof("leader: '%s' lost lease, holder - %s", service.name, service.holder)
+		}
+
+		return nil
+	}
+
+	if atomic.CompareAndSwapUint32(&service.isLeader, 0, 1) {
+		service.logger.Infof("leader: '%s' acquired lease, holder - %s", service.name, service.holder)
+	}
+
+	return service.process.Process(ctx)
+}
@@ -0,0 +1,104 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
+	"github.com/diez37/go-packages/log"
+	"sync"
+)
+
+var (
+	BufferFullError = errors.New("buffer is full")
+)
+
+// limiter bounding size of buffer of write-behind service and signaling when buffer must be written
+type limiter struct {
+	name   string
+	limit  int
+	flush  int
+	size   int
+	policy string
+
+	// freed closed and replaced on every release of buffer space
+	freed chan struct{}
+	// flushes signal for writing buffer without waiting of delay
+	flushes chan struct{}
+
+	logger  log.Logger
+	metrics *metrics.Metrics
+}
+
+func newLimiter(name string, limit uint, config *config.Token, logger log.Logger, metrics *metrics.Metrics) *limiter {
+	return &limiter{
+		name:    name,
+		limit:   int(limit),
+		flush:   int(config.BufferFlush),
+		size:    int(config.BufferChunk),
+		policy:  config.BufferOverflow,
+		freed:   make(chan struct{}),
+		flushes: make(chan struct{}, 1),
+		logger:  logger,
+		metrics: metrics,
+	}
+}
+
+// acquire waiting for space for count elements, must be called under locker and returns under it.
+// A buffer which is empty always accepts, so one oversized write cannot be rejected forever
+func (limiter *limiter) acquire(ctx context.Context, locker sync.Locker, length func() int, count int) error {
+	for limiter.limit > 0 && length() > 0 && length()+count > limiter.limit {
+		limiter.metrics.BufferOverflow.WithLabelValues(limiter.name, limiter.policy).Inc()
+
+		if limiter.policy != config.TokensBufferOverflowBlock {
+			limiter.logger.Warnf("%s: buffer is full, size - %d, limit - %d", limiter.name, length(), limiter.limit)
+
+			return BufferFullError
+		}
+
+		limiter.logger.Warnf("%s: buffer is full, waiting for free space, limit - %d", limiter.name, limiter.limit)
+
+		freed := limiter.freed
+
+		locker.Unlock()
+
+		select {
+		case <-ctx.Done():
+			locker.Lock()
+
+			return ctx.Err()
+		case <-freed:
+		}
+
+		locker.Lock()
+	}
+
+	return nil
+}
+
+// release waking up writers which wait for space, must be called under locker
+func (limiter *limiter) release() {
+	close(limiter.freed)
+	limiter.freed = make(chan struct{})
+}
+
+// notify requesting writing of buffer when it reached size of flush
+func (limiter *limiter) notify(length int) {
+	if limiter.flush == 0 || length < limiter.flush {
+		return
+	}
+
+	select {
+	case limiter.flushes <- struct{}{}:
+	default:
+	}
+}
+
+// chunk return size of next request to repository for buffer of length
+func (limiter *limiter) chunk(length int) int {
+	if limiter.size == 0 || limiter.size > length {
+		return length
+	}
+
+	return limiter.size
+}
@@ -0,0 +1,214 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/journal"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+// countingInserts repository which counts inserts and fails them after number of successful inserts, negative number never fails
+type countingInserts struct {
+	repository.Repository
+	inserts   []int
+	successes int
+}
+
+func (repository *countingInserts) Insert(ctx context.Context, tokens ...*repository.RefreshToken) error {
+	if repository.successes == 0 {
+		return errors.New("repository is down")
+	}
+
+	repository.successes--
+	repository.inserts = append(repository.inserts, len(tokens))
+
+	return repository.Repository.Insert(ctx, tokens...)
+}
+
+func newTestSaver(tokenRepository repository.Repository, tokenConfig *config.Token) Saver {
+	return NewSaver(tokenRepository, journal.NewNop(), tokenConfig, testLogger(), testMetrics, testTracer)
+}
+
+func TestSaverFailsOnFullBuffer(t *testing.T) {
+	ctx := context.Background()
+
+	tokenConfig := testTokenConfig()
+	tokenConfig.SaverLimit = 2
+	tokenConfig.BufferOverflow = config.TokensBufferOverflowFail
+
+	saver := newTestSaver(repository.NewMemory(testTracer), tokenConfig)
+
+	if err := saver.Insert(ctx, newTestToken(uuid.New()), newTestToken(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saver.Insert(ctx, newTestToken(uuid.New())); err != BufferFullError {
+		t.Fatalf("insert to full buffer, error %v, want BufferFullError", err)
+	}
+
+	if err := saver.Process(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saver.Insert(ctx, newTestToken(uuid.New())); err != nil {
+		t.Fatalf("insert after save: %s", err)
+	}
+}
+
+func TestSaverAcceptsOversizedWriteToEmptyBuffer(t *testing.T) {
+	tokenConfig := testTokenConfig()
+	tokenConfig.SaverLimit = 1
+	tokenConfig.BufferOverflow = config.TokensBufferOverflowFail
+
+	saver := newTestSaver(repository.NewMemory(testTracer), tokenConfig)
+
+	if err := saver.Insert(context.Background(), newTestToken(uuid.New()), newTestToken(uuid.New())); err != nil {
+		t.Fatalf("oversized insert to empty buffer: %s", err)
+	}
+}
+
+func TestSaverBlocksOnFullBuffer(t *testing.T) {
+	ctx := context.Background()
+
+	tokenConfig := testTokenConfig()
+	tokenConfig.SaverLimit = 1
+	tokenConfig.BufferOverflow = config.TokensBufferOverflowBlock
+
+	saver := newTestSaver(repository.NewMemory(testTracer), tokenConfig)
+
+	if err := saver.Insert(ctx, newTestToken(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	// the waiting is interrupted by ctx
+	timeoutCtx, cancelFunc := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelFunc()
+
+	if err := saver.Insert(timeoutCtx, newTestToken(uuid.New())); err != context.DeadlineExceeded {
+		t.Fatalf("insert to full buffer, error %v, want context.DeadlineExceeded", err)
+	}
+
+	inserted := make(chan error, 1)
+	go func() {
+		inserted <- saver.Insert(ctx, newTestToken(uuid.New()))
+	}()
+
+	select {
+	case err := <-inserted:
+		t.Fatalf("insert to full buffer returned %v before save", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := saver.Process(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-inserted:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("insert is not woken up by save")
+	}
+}
+
+func TestSaverSignalsFlushBySize(t *testing.T) {
+	tokenConfig := testTokenConfig()
+	tokenConfig.BufferFlush = 2
+
+	saver := newTestSaver(repository.NewMemory(testTracer), tokenConfig)
+
+	if err := saver.Insert(context.Background(), newTestToken(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-saver.Flush():
+		t.Fatal("flush is signaled before size of flush")
+	default:
+	}
+
+	if err := saver.Insert(context.Background(), newTestToken(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-saver.Flush():
+	default:
+		t.Fatal("flush is not signaled at size of flush")
+	}
+}
+
+func TestSaverInsertsByChunks(t *testing.T) {
+	ctx := context.Background()
+
+	tokenConfig := testTokenConfig()
+	tokenConfig.BufferChunk = 2
+
+	tokenRepository := &countingInserts{Repository: repository.NewMemory(testTracer), successes: 1}
+	saver := newTestSaver(tokenRepository, tokenConfig)
+
+	tokens := []*repository.RefreshToken{
+		newTestToken(uuid.New()), newTestToken(uuid.New()), newTestToken(uuid.New()), newTestToken(uuid.New()), newTestToken(uuid.New()),
+	}
+
+	if err := saver.Insert(ctx, tokens...); err != nil {
+		t.Fatal(err)
+	}
+
+	// the saved chunk leaves buffer, the failed chunks wait for next run
+	if err := saver.Process(ctx); err == nil {
+		t.Fatal("expected error of failed chunk")
+	}
+
+	if size := saver.Size(); size != 3 {
+		t.Fatalf("%d tokens in buffer after failed chunk, want 3", size)
+	}
+
+	tokenRepository.successes = -1
+
+	if err := saver.Process(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokenRepository.inserts) != 3 || tokenRepository.inserts[0] != 2 || tokenRepository.inserts[1] != 2 || tokenRepository.inserts[2] != 1 {
+		t.Fatalf("sizes of inserts %v, want [2 2 1]", tokenRepository.inserts)
+	}
+
+	for _, token := range tokens {
+		if _, err := tokenRepository.FindByUUID(ctx, token.UUID); err != nil {
+			t.Fatalf("token '%s' is not saved: %s", token.UUID, err)
+		}
+	}
+}
+
+func TestBlockerFailsOnFullQueue(t *testing.T) {
+	ctx := context.Background()
+
+	tokenConfig := testTokenConfig()
+	tokenConfig.BlockerLimit = 2
+	tokenConfig.BufferOverflow = config.TokensBufferOverflowFail
+
+	blocker := NewBlocker(repository.NewMemory(testTracer), journal.NewNop(), tokenConfig, testLogger(), testMetrics, testTracer)
+
+	if err := blocker.BlockByUUID(ctx, repository.RevokeReasonLogout, uuid.New(), uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := blocker.BlockByUUID(ctx, repository.RevokeReasonLogout, uuid.New()); err != BufferFullError {
+		t.Fatalf("block to full queue, error %v, want BufferFullError", err)
+	}
+
+	if err := blocker.Process(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := blocker.BlockByUUID(ctx, repository.RevokeReasonLogout, uuid.New()); err != nil {
+		t.Fatalf("block after apply: %s", err)
+	}
+}
@@ -0,0 +1,115 @@
+package application
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/domain"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/repeater"
+	"github.com/google/uuid"
+)
+
+const (
+	OperationCreate     = "create"
+	OperationRefresh    = "refresh"
+	OperationDisable    = "disable"
+	OperationDisableAll = "disable_all"
+	OperationValidation = "validation"
+
+	OutcomeSuccess = "success"
+	OutcomeDenied  = "denied"
+	OutcomeError   = "error"
+)
+
+type measuredToken struct {
+	service Token
+	metrics *metrics.Metrics
+}
+
+// NewMeasuredToken creating Token which counts operations by outcome, every error of validation is denial
+func NewMeasuredToken(service Token, metrics *metrics.Metrics) Token {
+	return &measuredToken{service: service, metrics: metrics}
+}
+
+func (service *measuredToken) Create(ctx context.Context, token *domain.RefreshToken) (*domain.RefreshToken, string, error) {
+	token, jwt, err := service.service.Create(ctx, token)
+	service.count(OperationCreate, err)
+
+	return token, jwt, err
+}
+
+func (service *measuredToken) Refresh(ctx context.Context, token *domain.RefreshToken) (*domain.RefreshToken, string, error) {
+	token, jwt, err := service.service.Refresh(ctx, token)
+	service.count(OperationRefresh, err)
+
+	return token, jwt, err
+}
+
+func (service *measuredToken) DisableAll(ctx context.Context, reason repository.RevokeReason, login uuid.UUID, exclude ...uuid.UUID) error {
+	err := service.service.DisableAll(ctx, reason, login, exclude...)
+	service.count(OperationDisableAll, err)
+
+	return err
+}
+
+func (service *measuredToken) Disable(ctx context.Context, reason repository.RevokeReason, uuid uuid.UUID) error {
+	err := service.service.Disable(ctx, reason, uuid)
+	service.count(OperationDisable, err)
+
+	return err
+}
+
+func (service *measuredToken) Validation(ctx context.Context, token string) error {
+	err := service.service.Validation(ctx, token)
+
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeDenied
+	}
+
+	service.metrics.TokenOperations.WithLabelValues(OperationValidation, outcome).Inc()
+
+	return err
+}
+
+func (service *measuredToken) Parse(ctx context.Context, token string) (*domain.JwtClaims, error) {
+	return service.service.Parse(ctx, token)
+}
+
+func (service *measuredToken) Sessions(ctx context.Context, login uuid.UUID) ([]*domain.Session, error) {
+	return service.service.Sessions(ctx, login)
+}
+
+func (service *measuredToken) count(operation string, err error) {
+	outcome := OutcomeSuccess
+
+	switch {
+	case err == AccessDeniedError:
+		outcome = OutcomeDenied
+	case err != nil:
+		outcome = OutcomeError
+	}
+
+	service.metrics.TokenOperations.WithLabelValues(operation, outcome).Inc()
+}
+
+type measuredProcess struct {
+	name    string
+	process repeater.Process
+	metrics *metrics.Metrics
+}
+
+// NewMeasuredProcess creating repeater.Process which counts failed runs of process
+func NewMeasuredProcess(name string, process repeater.Process, metrics *metrics.Metrics) repeater.Process {
+	return &measuredProcess{name: name, process: process, metrics: metrics}
+}
+
+func (service *measuredProcess) Process(ctx context.Context) error {
+	if err := service.process.Process(ctx); err != nil {
+		service.metrics.ProcessFailures.WithLabelValues(service.name).Inc()
+
+		return err
+	}
+
+	return nil
+}
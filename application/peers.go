@@ -0,0 +1,348 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Diez37/go-skeleton/infrastructure/broker"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/diez37/go-packages/log"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	PeersInsertTopic = "tokenizer.tokens.insert"
+	PeersBlockTopic  = "tokenizer.tokens.block"
+
+	// peersBackoffInitial and peersBackoffMaximum delays before resubscribe after failed subscription,
+	// the delay doubles for every next failure
+	peersBackoffInitial = time.Second
+	peersBackoffMaximum = 30 * time.Second
+)
+
+var (
+	PeersNotSubscribedError = errors.New("peers: not subscribed to broker")
+)
+
+// Peers Cache which shares accepted but not yet written inserts and blocks with other replicas
+type Peers interface {
+	Cache
+
+	// Serve receiving inserts and blocks of other replicas until ctx is done,
+	// the failed subscription is repeated with backoff
+	Serve(ctx context.Context) error
+
+	// Ready checking that inserts and blocks of other replicas are received
+	Ready(ctx context.Context) error
+}
+
+type peerMessage struct {
+	Sender uuid.UUID                  `json:"sender"`
+	Tokens []*repository.RefreshToken `json:"tokens,omitempty"`
+	UUIDs  []uuid.UUID                `json:"uuids,omitempty"`
+	Reason repository.RevokeReason    `json:"reason,omitempty"`
+}
+
+type peerToken struct {
+	token      *repository.RefreshToken
+	receivedAt time.Time
+}
+
+type peers struct {
+	rwMutex *sync.RWMutex
+
+	id     uuid.UUID
+	cache  Cache
+	broker broker.Broker
+	ttl    time.Duration
+
+	// tokens inserted by other replicas, they live ttl which must be longer than delay of saver
+	tokens map[uuid.UUID]*peerToken
+	// subscribed 1 while subscription to broker is active
+	subscribed uint32
+
+	logger log.Logger
+	tracer trace.Tracer
+}
+
+func NewPeers(cache Cache, broker broker.Broker, config *config.Token, logger log.Logger, tracer trace.Tracer) Peers {
+	return &peers{
+		rwMutex: &sync.RWMutex{},
+		id:      uuid.New(),
+		cache:   cache,
+		broker:  broker,
+		ttl:     config.PeersTTL,
+		tokens:  map[uuid.UUID]*peerToken{},
+		logger:  logger,
+		tracer:  tracer,
+	}
+}
+
+func (service *peers) Serve(ctx context.Context) error {
+	service.logger.Infof("peers: started, id - %s", service.id)
+
+	backoff := peersBackoffInitial
+
+	for {
+		startedAt := time.Now()
+
+		err := service.subscribe(ctx)
+		atomic.StoreUint32(&service.subscribed, 0)
+
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// the subscription which lived longer than backoff was healthy, the next failure starts backoff again
+		if time.Since(startedAt) > backoff {
+			backoff = peersBackoffInitial
+		}
+
+		if err == nil {
+			err = errors.New("subscription closed")
+		}
+
+		service.logger.Errorf("peers: subscription error - %s, resubscribe after %s", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > peersBackoffMaximum {
+			backoff = peersBackoffMaximum
+		}
+	}
+}
+
+func (service *peers) Ready(_ context.Context) error {
+	if atomic.LoadUint32(&service.subscribed) == 0 {
+		return PeersNotSubscribedError
+	}
+
+	return nil
+}
+
+// subscribe receiving inserts and blocks of other replicas until ctx is done or subscription failed
+func (service *peers) subscribe(ctx context.Context) error {
+	subscribed := func() {
+		atomic.StoreUint32(&service.subscribed, 1)
+	}
+
+	return service.broker.Subscribe(ctx, subscribed, func(topic string, payload []byte) {
+		message := &peerMessage{}
+		if err := json.Unmarshal(payload, message); err != nil {
+			service.logger.Errorf("peers: message of topic '%s', error - %s", topic, err)
+			return
+		}
+
+		if message.Sender == service.id {
+			return
+		}
+
+		switch topic {
+		case PeersInsertTopic:
+			service.receiveInsert(message.Tokens...)
+		case PeersBlockTopic:
+			if err := service.receiveBlock(ctx, message.Reason, message.UUIDs...); err != nil {
+				service.logger.Errorf("peers: block from '%s', error - %s", message.Sender, err)
+			}
+		}
+	}, PeersInsertTopic, PeersBlockTopic)
+}
+
+func (service *peers) Restore(ctx context.Context) error {
+	return service.cache.Restore(ctx)
+}
+
+func (service *peers) FindByLogin(ctx context.Context, login uuid.UUID) ([]*repository.RefreshToken, error) {
+	ctx, span := service.tracer.Start(ctx, "finder.login")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("login", login.String()),
+		attribute.String("repository", "service"),
+		attribute.String("service", "peers"),
+	)
+
+	tokens, err := service.cache.FindByLogin(ctx, login)
+	if err != nil && err != db.RecordNotFoundError {
+		return nil, err
+	}
+
+	exists := make(map[uuid.UUID]bool, len(tokens))
+	for _, token := range tokens {
+		exists[token.UUID] = true
+	}
+
+	service.rwMutex.RLock()
+	defer service.rwMutex.RUnlock()
+
+	now := time.Now().In(time.UTC)
+
+	for _, peerToken := range service.tokens {
+		if peerToken.token.Login == login && !exists[peerToken.token.UUID] && service.isActual(peerToken, now) {
+			tokens = append(tokens, peerToken.token)
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, db.RecordNotFoundError
+	}
+
+	return tokens, nil
+}
+
+func (service *peers) FindAllByLogin(ctx context.Context, login uuid.UUID) ([]*repository.RefreshToken, error) {
+	ctx, span := service.tracer.Start(ctx, "finder.all.login")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("login", login.String()),
+		attribute.String("repository", "service"),
+		attribute.String("service", "peers"),
+	)
+
+	tokens, err := service.cache.FindAllByLogin(ctx, login)
+	if err != nil && err != db.RecordNotFoundError {
+		return nil, err
+	}
+
+	exists := make(map[uuid.UUID]bool, len(tokens))
+	for _, token := range tokens {
+		exists[token.UUID] = true
+	}
+
+	service.rwMutex.RLock()
+	defer service.rwMutex.RUnlock()
+
+	now := time.Now().In(time.UTC)
+
+	for _, peerToken := range service.tokens {
+		if peerToken.token.Login == login && !exists[peerToken.token.UUID] && service.isActual(peerToken, now) {
+			tokens = append(tokens, peerToken.token)
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, db.RecordNotFoundError
+	}
+
+	return tokens, nil
+}
+
+func (service *peers) FindByUUID(ctx context.Context, uuid uuid.UUID) (*repository.RefreshToken, error) {
+	ctx, span := service.tracer.Start(ctx, "finder.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", "service"),
+		attribute.String("service", "peers"),
+	)
+
+	token, err := service.cache.FindByUUID(ctx, uuid)
+	if err != db.RecordNotFoundError {
+		return token, err
+	}
+
+	service.rwMutex.RLock()
+	defer service.rwMutex.RUnlock()
+
+	if peerToken, exist := service.tokens[uuid]; exist && service.isActual(peerToken, time.Now().In(time.UTC)) {
+		return peerToken.token, nil
+	}
+
+	return nil, db.RecordNotFoundError
+}
+
+func (service *peers) FindRevokedByUUID(ctx context.Context, uuid uuid.UUID) (*repository.RefreshToken, error) {
+	return service.cache.FindRevokedByUUID(ctx, uuid)
+}
+
+func (service *peers) Insert(ctx context.Context, tokens ...*repository.RefreshToken) error {
+	if err := service.cache.Insert(ctx, tokens...); err != nil {
+		return err
+	}
+
+	// the replicas see the token after it is saved to repository, a failed publish is not an error of insert
+	if err := service.publish(ctx, PeersInsertTopic, &peerMessage{Sender: service.id, Tokens: tokens}); err != nil {
+		service.logger.Errorf("peers: topic '%s', error - %s", PeersInsertTopic, err)
+	}
+
+	return nil
+}
+
+func (service *peers) BlockByUUID(ctx context.Context, reason repository.RevokeReason, uuids ...uuid.UUID) error {
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	if err := service.receiveBlock(ctx, reason, uuids...); err != nil {
+		return err
+	}
+
+	// the token in buffer of other replica is saved active without the block, the caller repeats failed block
+	return service.publish(ctx, PeersBlockTopic, &peerMessage{Sender: service.id, UUIDs: uuids, Reason: reason})
+}
+
+func (service *peers) BlockByDate(ctx context.Context, date time.Time) error {
+	return service.cache.BlockByDate(ctx, date)
+}
+
+func (service *peers) Purge(ctx context.Context, date time.Time) error {
+	return service.cache.Purge(ctx, date)
+}
+
+// publish sending message to other replicas
+func (service *peers) publish(ctx context.Context, topic string, message *peerMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	return service.broker.Publish(ctx, topic, payload)
+}
+
+func (service *peers) receiveInsert(tokens ...*repository.RefreshToken) {
+	service.rwMutex.Lock()
+	defer service.rwMutex.Unlock()
+
+	now := time.Now().In(time.UTC)
+
+	for uuid, peerToken := range service.tokens {
+		if !service.isActual(peerToken, now) {
+			delete(service.tokens, uuid)
+		}
+	}
+
+	for _, token := range tokens {
+		service.tokens[token.UUID] = &peerToken{token: token, receivedAt: now}
+	}
+}
+
+// receiveBlock applying block locally, it cancels the token in buffer of saver of this replica
+// and hides the token of other replica
+func (service *peers) receiveBlock(ctx context.Context, reason repository.RevokeReason, uuids ...uuid.UUID) error {
+	service.rwMutex.Lock()
+
+	for _, uuid := range uuids {
+		delete(service.tokens, uuid)
+	}
+
+	service.rwMutex.Unlock()
+
+	return service.cache.BlockByUUID(ctx, reason, uuids...)
+}
+
+func (service *peers) isActual(peerToken *peerToken, now time.Time) bool {
+	return now.Sub(peerToken.receivedAt) < service.ttl
+}
@@ -0,0 +1,229 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Diez37/go-skeleton/infrastructure/broker"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/google/uuid"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestPeers replica with own buffers over shared repository and broker, it is served until end of test
+func newTestPeers(t *testing.T, tokenRepository repository.Repository, tokenBroker broker.Broker) (Peers, *testWriteBehind) {
+	t.Helper()
+
+	writeBehind := newTestWriteBehind(t, tokenRepository, testTokenConfig(), "")
+	peers := NewPeers(writeBehind.cache, tokenBroker, testTokenConfig(), testLogger(), testTracer)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		if err := peers.Serve(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	t.Cleanup(func() {
+		cancelFunc()
+		wg.Wait()
+	})
+
+	waitSubscribed(t, tokenBroker, peers)
+
+	return peers, writeBehind
+}
+
+// waitSubscribed publishing probe token of unknown replica until peers received it
+func waitSubscribed(t *testing.T, tokenBroker broker.Broker, peers Peers) {
+	t.Helper()
+
+	probe := newTestToken(uuid.New())
+
+	payload, err := json.Marshal(&peerMessage{Sender: uuid.New(), Tokens: []*repository.RefreshToken{probe}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, func() bool {
+		if err := tokenBroker.Publish(context.Background(), PeersInsertTopic, payload); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := peers.FindByUUID(context.Background(), probe.UUID)
+
+		return err == nil
+	})
+}
+
+func TestPeersInsert(t *testing.T) {
+	tokenRepository := repository.NewMemory(testTracer)
+	tokenBroker := broker.NewMemory()
+
+	first, _ := newTestPeers(t, tokenRepository, tokenBroker)
+	second, _ := newTestPeers(t, tokenRepository, tokenBroker)
+
+	token := newTestToken(uuid.New())
+	if err := first.Insert(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	// the token is not yet saved, other replica reads it from message of broker
+	if _, err := tokenRepository.FindByUUID(context.Background(), token.UUID); err != db.RecordNotFoundError {
+		t.Fatalf("token is saved before flush, error - %v", err)
+	}
+
+	eventually(t, func() bool {
+		_, err := second.FindByUUID(context.Background(), token.UUID)
+		return err == nil
+	})
+
+	tokens, err := second.FindByLogin(context.Background(), token.Login)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) != 1 || tokens[0].UUID != token.UUID {
+		t.Fatalf("tokens of login %v, want %s", tokens, token.UUID)
+	}
+}
+
+func TestPeersBlockCancelsTokenOfOtherReplica(t *testing.T) {
+	tokenRepository := repository.NewMemory(testTracer)
+	tokenBroker := broker.NewMemory()
+
+	first, firstWriteBehind := newTestPeers(t, tokenRepository, tokenBroker)
+	second, secondWriteBehind := newTestPeers(t, tokenRepository, tokenBroker)
+
+	token := newTestToken(uuid.New())
+	if err := first.Insert(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, func() bool {
+		_, err := second.FindByUUID(context.Background(), token.UUID)
+		return err == nil
+	})
+
+	if err := second.BlockByUUID(context.Background(), repository.RevokeReasonLogout, token.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the block is received by the first replica and the token is canceled in its saver
+	eventually(t, func() bool {
+		_, err := first.FindByUUID(context.Background(), token.UUID)
+		return err == db.RecordNotFoundError
+	})
+
+	if _, err := second.FindByUUID(context.Background(), token.UUID); err != db.RecordNotFoundError {
+		t.Fatalf("blocked token is found by second replica, error - %v", err)
+	}
+
+	firstWriteBehind.flush(t)
+	secondWriteBehind.flush(t)
+
+	if _, err := tokenRepository.FindByUUID(context.Background(), token.UUID); err != db.RecordNotFoundError {
+		t.Fatalf("blocked token is saved, error - %v", err)
+	}
+}
+
+// failingPublish broker which fails publish
+type failingPublish struct {
+	broker.Broker
+}
+
+func (broker *failingPublish) Publish(_ context.Context, _ string, _ []byte) error {
+	return errors.New("broker is unavailable")
+}
+
+func TestPeersBlockReturnsFailedPublish(t *testing.T) {
+	tokenRepository := repository.NewMemory(testTracer)
+
+	writeBehind := newTestWriteBehind(t, tokenRepository, testTokenConfig(), "")
+	peers := NewPeers(writeBehind.cache, &failingPublish{Broker: broker.NewMemory()}, testTokenConfig(), testLogger(), testTracer)
+
+	token := newTestToken(uuid.New())
+
+	// the insert is seen by other replicas after save
+	if err := peers.Insert(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	// the block not received by other replicas is lost for token in their buffers
+	if err := peers.BlockByUUID(context.Background(), repository.RevokeReasonLogout, token.UUID); err == nil {
+		t.Fatal("block with failed publish succeeded")
+	}
+}
+
+// failingBroker broker which subscription fails the first failures times
+type failingBroker struct {
+	broker.Broker
+
+	failures int32
+}
+
+func (broker *failingBroker) Subscribe(ctx context.Context, subscribed func(), handler broker.Handler, topics ...string) error {
+	if atomic.AddInt32(&broker.failures, -1) >= 0 {
+		return errors.New("broker is unavailable")
+	}
+
+	return broker.Broker.Subscribe(ctx, subscribed, handler, topics...)
+}
+
+func TestPeersResubscribe(t *testing.T) {
+	tokenRepository := repository.NewMemory(testTracer)
+	tokenBroker := &failingBroker{Broker: broker.NewMemory(), failures: 1}
+
+	writeBehind := newTestWriteBehind(t, tokenRepository, testTokenConfig(), "")
+	peers := NewPeers(writeBehind.cache, tokenBroker, testTokenConfig(), testLogger(), testTracer)
+
+	if err := peers.Ready(context.Background()); err != PeersNotSubscribedError {
+		t.Fatalf("peers are ready before serve, error - %v", err)
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	served := make(chan struct{})
+
+	go func() {
+		defer close(served)
+
+		if err := peers.Serve(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// the second subscription starts after backoff
+	for deadline := peersBackoffInitial * 2; deadline > 0 && atomic.LoadInt32(&tokenBroker.failures) >= 0; deadline -= peersBackoffInitial / 10 {
+		if err := peers.Ready(ctx); err != PeersNotSubscribedError {
+			t.Fatalf("peers are ready with failed subscription, error - %v", err)
+		}
+
+		select {
+		case <-served:
+			t.Fatal("serve stopped after failed subscription")
+		case <-time.After(peersBackoffInitial / 10):
+		}
+	}
+
+	waitSubscribed(t, tokenBroker, peers)
+
+	if err := peers.Ready(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	cancelFunc()
+	<-served
+
+	if err := peers.Ready(context.Background()); err != PeersNotSubscribedError {
+		t.Fatalf("peers are ready after serve, error - %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package application
+
+import (
+	"fmt"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"strings"
+	"sync/atomic"
+)
+
+// Policy settings of tokens which are reloaded without restart: lifetime of access token, maximum tokens,
+// checked fields of refresh and action on violation. Get returns snapshot which is never changed, so
+// readers see consistent settings while Reload replaces the snapshot as a whole
+type Policy interface {
+	Get() *config.Token
+
+	// Reload replacing reloadable settings by settings of next, it returns descriptions of changed settings
+	Reload(next *config.Token) []string
+}
+
+type policy struct {
+	current atomic.Value
+}
+
+// NewPolicy creating Policy of copy of config
+func NewPolicy(config *config.Token) Policy {
+	snapshot := *config
+
+	policy := &policy{}
+	policy.current.Store(&snapshot)
+
+	return policy
+}
+
+func (policy *policy) Get() *config.Token {
+	return policy.current.Load().(*config.Token)
+}
+
+func (policy *policy) Reload(next *config.Token) []string {
+	current := policy.Get()
+	snapshot := *current
+
+	var diff []string
+
+	if current.AccessLifetime != next.AccessLifetime {
+		diff = append(diff, fmt.Sprintf("%s: %s -> %s", config.TokensAccessLifetimeFieldName, current.AccessLifetime, next.AccessLifetime))
+		snapshot.AccessLifetime = next.AccessLifetime
+	}
+
+	if current.MaximumTokens != next.MaximumTokens {
+		diff = append(diff, fmt.Sprintf("%s: %d -> %d", config.TokensMaximumTokensFieldName, current.MaximumTokens, next.MaximumTokens))
+		snapshot.MaximumTokens = next.MaximumTokens
+	}
+
+	if strings.Join(current.RefreshCheckFields, ",") != strings.Join(next.RefreshCheckFields, ",") {
+		diff = append(diff, fmt.Sprintf(
+			"%s: [%s] -> [%s]",
+			config.TokensCheckFieldsForRefreshFieldName,
+			strings.Join(current.RefreshCheckFields, ","),
+			strings.Join(next.RefreshCheckFields, ","),
+		))
+		snapshot.RefreshCheckFields = append([]string(nil), next.RefreshCheckFields...)
+	}
+
+	if current.AccessViolation != next.AccessViolation {
+		diff = append(diff, fmt.Sprintf("%s: %s -> %s", config.TokensRefreshActionOnAccessViolation, current.AccessViolation, next.AccessViolation))
+		snapshot.AccessViolation = next.AccessViolation
+	}
+
+	if len(diff) > 0 {
+		policy.current.Store(&snapshot)
+	}
+
+	return diff
+}
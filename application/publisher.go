@@ -0,0 +1,12 @@
+package application
+
+import (
+	"context"
+	"github.com/google/uuid"
+)
+
+// EventPublisher sink of events: webhook, message broker or stdout
+type EventPublisher interface {
+	// Publish delivering payload of event, delivery is the same for every retry of the event
+	Publish(ctx context.Context, delivery uuid.UUID, eventType string, payload []byte) error
+}
@@ -0,0 +1,39 @@
+package application
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/repeater"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+type Retention interface {
+	repeater.Process
+}
+
+type retention struct {
+	repository repository.Archiver
+	config     *config.Token
+	tracer     trace.Tracer
+}
+
+// NewRetention creating process which removes tokens archived longer than retention period
+func NewRetention(repository repository.Archiver, config *config.Token, tracer trace.Tracer) Retention {
+	return &retention{repository: repository, config: config, tracer: tracer}
+}
+
+func (service *retention) Process(ctx context.Context) error {
+	ctx, span := service.tracer.Start(ctx, "service.retention.process")
+	defer span.End()
+
+	if service.config.ArchiveRetention <= 0 {
+		return nil
+	}
+
+	span.SetAttributes(attribute.String("retention", service.config.ArchiveRetention.String()))
+
+	return service.repository.Purge(ctx, time.Now().In(time.UTC).Add(-service.config.ArchiveRetention))
+}
@@ -2,44 +2,92 @@ package application
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/journal"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
 	"github.com/Diez37/go-skeleton/infrastructure/repository"
 	"github.com/diez37/go-packages/clients/db"
+	"github.com/diez37/go-packages/log"
 	"github.com/diez37/go-packages/repeater"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
 	"sync"
+	"sync/atomic"
 )
 
 const (
 	saverInitCap = 500
 )
 
+// Saver buffer of tokens waiting for save, the events attached to ctx of Insert wait with the tokens
+// and they are written to outbox in the same transaction as the tokens
 type Saver interface {
 	repeater.Process
 	repository.Finder
 	repository.Saver
+
+	// Restore returning to buffer the tokens from journal which are not in repository
+	Restore(ctx context.Context) error
+
+	// Cancel removing not yet saved tokens from buffer, it returns events of the removed tokens
+	Cancel(ctx context.Context, uuids ...uuid.UUID) ([]*repository.Event, error)
+
+	// Flush return channel which signals that buffer reached size of flush
+	Flush() <-chan struct{}
+
+	// Size return number of tokens waiting for save, it does not wait for running save
+	Size() int
+}
+
+// saverRecord record of journal of saver
+type saverRecord struct {
+	Token  *repository.RefreshToken `json:"token"`
+	Events []*repository.Event      `json:"events,omitempty"`
 }
 
 type saver struct {
 	rwMutex *sync.RWMutex
 
 	repository repository.Repository
+	journal    journal.Journal
 
 	models        []*repository.RefreshToken
 	modelsByLogin map[uuid.UUID][]*repository.RefreshToken
 	modelsByUUID  map[uuid.UUID]*repository.RefreshToken
-
-	tracer trace.Tracer
+	// events events of insert by uuid of its first token
+	events map[uuid.UUID][]*repository.Event
+	// size length of models, it is read without lock which is held by save
+	size int64
+	// persisted number of tokens at head of models which are saved and wait for truncate of journal
+	persisted int
+
+	limiter *limiter
+	metrics *metrics.Metrics
+	tracer  trace.Tracer
 }
 
-func NewSaver(tokenRepository repository.Repository, tracer trace.Tracer) Saver {
+func NewSaver(
+	tokenRepository repository.Repository,
+	journal journal.Journal,
+	config *config.Token,
+	logger log.Logger,
+	metrics *metrics.Metrics,
+	tracer trace.Tracer,
+) Saver {
 	return &saver{
 		rwMutex:       &sync.RWMutex{},
 		repository:    tokenRepository,
+		journal:       journal,
 		models:        make([]*repository.RefreshToken, 0, saverInitCap),
 		modelsByLogin: map[uuid.UUID][]*repository.RefreshToken{},
 		modelsByUUID:  map[uuid.UUID]*repository.RefreshToken{},
+		events:        map[uuid.UUID][]*repository.Event{},
+		limiter:       newLimiter("saver", config.SaverLimit, config, logger, metrics),
+		metrics:       metrics,
 		tracer:        tracer,
 	}
 }
@@ -55,14 +103,93 @@ func (service *saver) Process(ctx context.Context) error {
 		return nil
 	}
 
-	err := service.repository.Insert(ctx, service.models...)
-	if err == nil {
-		service.models = make([]*repository.RefreshToken, 0, saverInitCap)
-		service.modelsByLogin = map[uuid.UUID][]*repository.RefreshToken{}
-		service.modelsByUUID = map[uuid.UUID]*repository.RefreshToken{}
+	span.SetAttributes(attribute.Int("length", len(service.models)))
+
+	for saved := service.persisted; saved < len(service.models); {
+		size := service.limiter.chunk(len(service.models) - saved)
+		chunk := service.models[saved : saved+size]
+
+		if err := service.repository.Insert(repository.WithEvents(ctx, service.eventsOf(chunk...)...), chunk...); err != nil {
+			if saved == service.persisted {
+				return err
+			}
+
+			// the saved chunks leave buffer, next run starts from the failed chunk
+			return multierr.Append(err, service.replace(service.models[saved:]...))
+		}
+
+		saved += size
+	}
+
+	// the saved tokens are read from repository, they leave buffer with the journal
+	service.forget(service.models[service.persisted:]...)
+	service.persisted = len(service.models)
+
+	if err := service.journal.Truncate(); err != nil {
+		return err
 	}
 
-	return err
+	service.reset()
+
+	return nil
+}
+
+func (service *saver) Flush() <-chan struct{} {
+	return service.limiter.flushes
+}
+
+func (service *saver) Size() int {
+	return int(atomic.LoadInt64(&service.size))
+}
+
+func (service *saver) Restore(ctx context.Context) error {
+	ctx, span := service.tracer.Start(ctx, "service.saver.restore")
+	defer span.End()
+
+	var records []*saverRecord
+
+	err := service.journal.Replay(func(data []byte) error {
+		record := &saverRecord{}
+		if err := json.Unmarshal(data, record); err != nil {
+			return err
+		}
+
+		if record.Token == nil {
+			return errors.New("saver: journal record without token")
+		}
+
+		// the saved token was written together with its events, it may be revoked or archived since
+		_, err := service.repository.FindByUUID(ctx, record.Token.UUID)
+		if err != db.RecordNotFoundError {
+			return err
+		}
+
+		_, err = service.repository.FindRevokedByUUID(ctx, record.Token.UUID)
+		if err == db.RecordNotFoundError {
+			records = append(records, record)
+			return nil
+		}
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int("length", len(records)))
+
+	service.rwMutex.Lock()
+	defer service.rwMutex.Unlock()
+
+	for _, record := range records {
+		service.add(record.Token)
+
+		if len(record.Events) > 0 {
+			service.events[record.Token.UUID] = record.Events
+		}
+	}
+
+	return nil
 }
 
 func (service *saver) FindByLogin(ctx context.Context, login uuid.UUID) ([]*repository.RefreshToken, error) {
@@ -94,6 +221,35 @@ func (service *saver) FindByLogin(ctx context.Context, login uuid.UUID) ([]*repo
 	return tokens, nil
 }
 
+func (service *saver) FindAllByLogin(ctx context.Context, login uuid.UUID) ([]*repository.RefreshToken, error) {
+	ctx, span := service.tracer.Start(ctx, "finder.all.login")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("login", login.String()),
+		attribute.String("repository", "service"),
+		attribute.String("service", "saver"),
+	)
+
+	tokens, err := service.repository.FindAllByLogin(ctx, login)
+	if err != nil && err != db.RecordNotFoundError {
+		return nil, err
+	}
+
+	service.rwMutex.RLock()
+	defer service.rwMutex.RUnlock()
+
+	if tokensByLogin, exist := service.modelsByLogin[login]; exist {
+		tokens = append(tokens, tokensByLogin...)
+	}
+
+	if len(tokens) == 0 {
+		return nil, db.RecordNotFoundError
+	}
+
+	return tokens, nil
+}
+
 func (service *saver) FindByUUID(ctx context.Context, uuid uuid.UUID) (*repository.RefreshToken, error) {
 	ctx, span := service.tracer.Start(ctx, "finder.uuid")
 	defer span.End()
@@ -123,6 +279,19 @@ func (service *saver) FindByUUID(ctx context.Context, uuid uuid.UUID) (*reposito
 	return nil, db.RecordNotFoundError
 }
 
+func (service *saver) FindRevokedByUUID(ctx context.Context, uuid uuid.UUID) (*repository.RefreshToken, error) {
+	ctx, span := service.tracer.Start(ctx, "finder.revoked.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", "service"),
+		attribute.String("service", "saver"),
+	)
+
+	return service.repository.FindRevokedByUUID(ctx, uuid)
+}
+
 func (service *saver) Insert(ctx context.Context, tokens ...*repository.RefreshToken) error {
 	ctx, span := service.tracer.Start(ctx, "saver.insert")
 	defer span.End()
@@ -133,9 +302,138 @@ func (service *saver) Insert(ctx context.Context, tokens ...*repository.RefreshT
 		attribute.String("service", "saver"),
 	)
 
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	events := repository.EventsFromContext(ctx)
+
+	records := make([][]byte, 0, len(tokens))
+	for index, token := range tokens {
+		record := &saverRecord{Token: token}
+		if index == 0 {
+			record.Events = events
+		}
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		records = append(records, data)
+	}
+
+	service.rwMutex.Lock()
+	defer service.rwMutex.Unlock()
+
+	err := service.limiter.acquire(ctx, service.rwMutex, func() int { return len(service.models) }, len(tokens))
+	if err != nil {
+		return err
+	}
+
+	if err := service.journal.Append(records...); err != nil {
+		return err
+	}
+
+	service.add(tokens...)
+
+	if len(events) > 0 {
+		service.events[tokens[0].UUID] = events
+	}
+
+	service.limiter.notify(len(service.models))
+
+	return nil
+}
+
+func (service *saver) Cancel(ctx context.Context, uuids ...uuid.UUID) ([]*repository.Event, error) {
+	_, span := service.tracer.Start(ctx, "service.saver.cancel")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("length", len(uuids)))
+
 	service.rwMutex.Lock()
 	defer service.rwMutex.Unlock()
 
+	canceled := map[uuid.UUID]bool{}
+	for _, uuid := range uuids {
+		if _, exist := service.modelsByUUID[uuid]; exist {
+			canceled[uuid] = true
+		}
+	}
+
+	if len(canceled) == 0 {
+		return nil, nil
+	}
+
+	var events []*repository.Event
+
+	models := make([]*repository.RefreshToken, 0, len(service.models))
+	for _, token := range service.models[service.persisted:] {
+		if canceled[token.UUID] {
+			events = append(events, service.events[token.UUID]...)
+		} else {
+			models = append(models, token)
+		}
+	}
+
+	return events, service.replace(models...)
+}
+
+// replace replacing buffer and journal by tokens, the tokens keep their events, must be called under the write lock
+func (service *saver) replace(tokens ...*repository.RefreshToken) error {
+	events := make(map[uuid.UUID][]*repository.Event, len(service.events))
+
+	records := make([][]byte, 0, len(tokens))
+	for _, token := range tokens {
+		record := &saverRecord{Token: token, Events: service.events[token.UUID]}
+
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		if len(record.Events) > 0 {
+			events[token.UUID] = record.Events
+		}
+
+		records = append(records, data)
+	}
+
+	service.reset()
+	service.add(tokens...)
+	service.events = events
+
+	return service.journal.Rewrite(records...)
+}
+
+// eventsOf return events of inserts of tokens, must be called under the lock
+func (service *saver) eventsOf(tokens ...*repository.RefreshToken) []*repository.Event {
+	var events []*repository.Event
+
+	for _, token := range tokens {
+		events = append(events, service.events[token.UUID]...)
+	}
+
+	return events
+}
+
+// reset clearing buffer, must be called under the write lock
+func (service *saver) reset() {
+	service.models = make([]*repository.RefreshToken, 0, saverInitCap)
+	service.modelsByLogin = map[uuid.UUID][]*repository.RefreshToken{}
+	service.modelsByUUID = map[uuid.UUID]*repository.RefreshToken{}
+	service.events = map[uuid.UUID][]*repository.Event{}
+
+	service.persisted = 0
+
+	atomic.StoreInt64(&service.size, 0)
+	service.metrics.SaverBuffer.Set(0)
+	service.limiter.release()
+}
+
+// add putting tokens to buffer, must be called under the write lock
+func (service *saver) add(tokens ...*repository.RefreshToken) {
 	service.models = append(service.models, tokens...)
 
 	for _, token := range tokens {
@@ -147,5 +445,27 @@ func (service *saver) Insert(ctx context.Context, tokens ...*repository.RefreshT
 		service.modelsByUUID[token.UUID] = token
 	}
 
-	return nil
+	atomic.StoreInt64(&service.size, int64(len(service.models)))
+	service.metrics.SaverBuffer.Set(float64(len(service.models)))
+}
+
+// forget removing saved tokens from lookups of buffer, must be called under the write lock
+func (service *saver) forget(tokens ...*repository.RefreshToken) {
+	for _, token := range tokens {
+		delete(service.modelsByUUID, token.UUID)
+		delete(service.events, token.UUID)
+
+		byLogin := service.modelsByLogin[token.Login][:0]
+		for _, model := range service.modelsByLogin[token.Login] {
+			if model.UUID != token.UUID {
+				byLogin = append(byLogin, model)
+			}
+		}
+
+		if len(byLogin) == 0 {
+			delete(service.modelsByLogin, token.Login)
+		} else {
+			service.modelsByLogin[token.Login] = byLogin
+		}
+	}
 }
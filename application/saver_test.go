@@ -0,0 +1,174 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"github.com/Diez37/go-skeleton/domain"
+	"github.com/Diez37/go-skeleton/infrastructure/journal"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/google/uuid"
+	"path/filepath"
+	"testing"
+)
+
+// restoreSaver creating saver over journal of dir as after restart and restoring it
+func restoreSaver(t *testing.T, tokenRepository repository.Repository, dir string) Saver {
+	t.Helper()
+
+	saver := newTestWriteBehind(t, tokenRepository, testTokenConfig(), dir).saver
+	if err := saver.Restore(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return saver
+}
+
+func TestSaverRestoresTokensWithEvents(t *testing.T) {
+	dir := t.TempDir()
+	tokenRepository := repository.NewMemory(testTracer)
+	outbox := tokenRepository.(repository.Outbox)
+
+	first, second := newTestToken(uuid.New()), newTestToken(uuid.New())
+
+	saver := newTestWriteBehind(t, tokenRepository, testTokenConfig(), dir).saver
+	if err := saver.Insert(withEvent(t, context.Background(), outbox, domain.EventSessionCreated), first, second); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := restoreSaver(t, tokenRepository, dir)
+	if size := restored.Size(); size != 2 {
+		t.Fatalf("%d tokens are restored, want 2", size)
+	}
+
+	for _, token := range []*repository.RefreshToken{first, second} {
+		if _, err := restored.FindByUUID(context.Background(), token.UUID); err != nil {
+			t.Fatalf("restored token '%s' is not found: %s", token.UUID, err)
+		}
+	}
+
+	if err := restored.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if events := pending(t, outbox); len(events) != 1 || events[0].Type != domain.EventSessionCreated {
+		t.Fatalf("events in outbox %v, want restored '%s'", events, domain.EventSessionCreated)
+	}
+
+	// the journal is truncated after save
+	if size := restoreSaver(t, tokenRepository, dir).Size(); size != 0 {
+		t.Fatalf("%d tokens are restored after save, want 0", size)
+	}
+}
+
+func TestSaverRestoreSkipsSavedTokens(t *testing.T) {
+	dir := t.TempDir()
+	tokenRepository := repository.NewMemory(testTracer)
+
+	saved, lost := newTestToken(uuid.New()), newTestToken(uuid.New())
+
+	saver := newTestWriteBehind(t, tokenRepository, testTokenConfig(), dir).saver
+	if err := saver.Insert(context.Background(), saved, lost); err != nil {
+		t.Fatal(err)
+	}
+
+	// the process stopped after save and before truncate of journal
+	if err := tokenRepository.Insert(context.Background(), saved); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := restoreSaver(t, tokenRepository, dir)
+	if size := restored.Size(); size != 1 {
+		t.Fatalf("%d tokens are restored, want only not saved", size)
+	}
+
+	if err := restored.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tokenRepository.FindByUUID(context.Background(), lost.UUID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaverRestoreKeepsCanceledTokensOut(t *testing.T) {
+	dir := t.TempDir()
+	tokenRepository := repository.NewMemory(testTracer)
+
+	canceled, kept := newTestToken(uuid.New()), newTestToken(uuid.New())
+
+	saver := newTestWriteBehind(t, tokenRepository, testTokenConfig(), dir).saver
+	if err := saver.Insert(context.Background(), canceled, kept); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := saver.Cancel(context.Background(), canceled.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := restoreSaver(t, tokenRepository, dir)
+	if size := restored.Size(); size != 1 {
+		t.Fatalf("%d tokens are restored, want 1", size)
+	}
+
+	if _, err := restored.FindByUUID(context.Background(), kept.UUID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failingTruncate journal which fails truncate
+type failingTruncate struct {
+	journal.Journal
+}
+
+func (journal *failingTruncate) Truncate() error {
+	return errors.New("disk is full")
+}
+
+func TestSaverRestoreSkipsRevokedTokens(t *testing.T) {
+	dir := t.TempDir()
+	tokenRepository := repository.NewMemory(testTracer)
+
+	saverJournal, err := journal.NewFile(filepath.Join(dir, "saver.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer saverJournal.Close()
+
+	token := newTestToken(uuid.New())
+
+	saver := NewSaver(tokenRepository, &failingTruncate{Journal: saverJournal}, testTokenConfig(), testLogger(), testMetrics, testTracer)
+	if err := saver.Insert(context.Background(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := saver.Process(context.Background()); err == nil {
+		t.Fatal("process with failed truncate of journal succeeded")
+	}
+
+	// the saved token waits for truncate and is read from repository only
+	if tokens, err := saver.FindByLogin(context.Background(), token.Login); err != nil || len(tokens) != 1 {
+		t.Fatalf("tokens %v, error %v, want one saved token", tokens, err)
+	}
+
+	if err := tokenRepository.BlockByUUID(context.Background(), repository.RevokeReasonLogout, token.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the retry does not insert saved token again
+	if err := saver.Process(context.Background()); err == nil {
+		t.Fatal("process with failed truncate of journal succeeded")
+	}
+
+	if _, err := saver.Cancel(context.Background(), token.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	if size := restoreSaver(t, tokenRepository, dir).Size(); size != 0 {
+		t.Fatalf("%d tokens are restored, want revoked token skipped", size)
+	}
+
+	if _, err := tokenRepository.FindByUUID(context.Background(), token.UUID); err != db.RecordNotFoundError {
+		t.Fatalf("revoked token is active after restart, error %v", err)
+	}
+}
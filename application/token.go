@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"github.com/Diez37/go-skeleton/domain"
 	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/keyring"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
 	"github.com/Diez37/go-skeleton/infrastructure/repository"
 	"github.com/diez37/go-packages/clients/db"
 	"github.com/diez37/go-packages/log"
@@ -14,12 +16,18 @@ import (
 	"github.com/spf13/cast"
 	"github.com/thoas/go-funk"
 	"go.opentelemetry.io/otel/trace"
+	"sort"
 	"time"
 )
 
 const (
 	ExpiresInJwtFieldName = "exp"
 	LoginJwtFieldName     = "login"
+	KeyIdJwtHeaderName    = "kid"
+
+	// RefreshDenialNotFound and RefreshDenialExpired checks of refresh besides fields of config.Token.RefreshCheckFields
+	RefreshDenialNotFound = "not_found"
+	RefreshDenialExpired  = "expired"
 )
 
 var (
@@ -29,41 +37,50 @@ var (
 type Token interface {
 	Create(ctx context.Context, token *domain.RefreshToken) (*domain.RefreshToken, string, error)
 	Refresh(ctx context.Context, token *domain.RefreshToken) (*domain.RefreshToken, string, error)
-	DisableAll(ctx context.Context, login uuid.UUID, exclude ...uuid.UUID) error
-	Disable(ctx context.Context, uuid uuid.UUID) error
+	DisableAll(ctx context.Context, reason repository.RevokeReason, login uuid.UUID, exclude ...uuid.UUID) error
+	Disable(ctx context.Context, reason repository.RevokeReason, uuid uuid.UUID) error
 	Validation(ctx context.Context, token string) error
 	Parse(ctx context.Context, token string) (*domain.JwtClaims, error)
+	// Sessions sessions of login including revoked and archived, ordered by time of create
+	Sessions(ctx context.Context, login uuid.UUID) ([]*domain.Session, error)
 }
 
 type token struct {
-	logger log.Logger
-	config *config.Token
-	secret []byte
+	logger  log.Logger
+	policy  Policy
+	keyring keyring.Keyring
 
 	finder  repository.Finder
 	saver   repository.Saver
 	blocker repository.Blocker
+	events  Events
 	parser  *jwt.Parser
+	metrics *metrics.Metrics
 
 	tracer trace.Tracer
 }
 
 func NewToken(
-	config *config.Token,
+	policy Policy,
 	logger log.Logger,
 	finder repository.Finder,
 	saver repository.Saver,
 	blocker repository.Blocker,
+	events Events,
+	keyring keyring.Keyring,
+	metrics *metrics.Metrics,
 	tracer trace.Tracer,
 ) Token {
 	return &token{
-		config:  config,
+		policy:  policy,
 		finder:  finder,
 		saver:   saver,
 		blocker: blocker,
+		events:  events,
 		logger:  logger,
-		secret:  []byte(config.Secret),
+		keyring: keyring,
 		parser:  new(jwt.Parser),
+		metrics: metrics,
 		tracer:  tracer,
 	}
 }
@@ -79,13 +96,22 @@ func (service *token) Create(ctx context.Context, token *domain.RefreshToken) (*
 		return nil, "", err
 	}
 
-	if len(tokens) >= int(service.config.MaximumTokens) {
-		if err := service.blocker.BlockByUUID(ctx, tokens[0].UUID); err != nil {
+	// the oldest tokens are revoked so that login has maximum of tokens with the new one,
+	// the excess remains after lowering of maximum or after concurrent creates
+	if excess := len(tokens) - int(service.policy.Get().MaximumTokens) + 1; excess > 0 {
+		oldest := make([]*repository.RefreshToken, len(tokens))
+		copy(oldest, tokens)
+
+		sort.SliceStable(oldest, func(i, j int) bool {
+			return oldest[i].CreatedAt.Before(oldest[j].CreatedAt)
+		})
+
+		if err := service.block(ctx, repository.RevokeReasonLimit, oldest[:excess]...); err != nil {
 			return nil, "", err
 		}
 	}
 
-	return service.generate(ctx, token)
+	return service.generate(ctx, token, domain.EventSessionCreated, nil)
 }
 
 func (service *token) Refresh(ctx context.Context, token *domain.RefreshToken) (*domain.RefreshToken, string, error) {
@@ -98,41 +124,63 @@ func (service *token) Refresh(ctx context.Context, token *domain.RefreshToken) (
 	}
 
 	if err == db.RecordNotFoundError {
+		service.metrics.RefreshDenials.WithLabelValues(RefreshDenialNotFound).Inc()
+
+		if err := service.detectReuse(ctx, token); err != nil {
+			return nil, "", err
+		}
+
 		return nil, "", AccessDeniedError
 	}
 
 	if refreshToken.ExpiresIn.Sub(time.Now().In(time.UTC)) <= 0 {
-		if err := service.blocker.BlockByUUID(ctx, refreshToken.UUID); err != nil {
+		service.metrics.RefreshDenials.WithLabelValues(RefreshDenialExpired).Inc()
+
+		if err := service.block(ctx, repository.RevokeReasonExpired, refreshToken); err != nil {
 			return nil, "", err
 		}
 
 		return nil, "", AccessDeniedError
 	}
 
-	for _, fieldForCheck := range service.config.RefreshCheckFields {
+	var violations []*domain.Event
+
+	// the same snapshot of policy is used for checks and action on violation
+	policy := service.policy.Get()
+
+	for _, fieldForCheck := range policy.RefreshCheckFields {
+		violated := false
+
 		switch fieldForCheck {
 		case config.TokenRefreshFieldIp:
-			if refreshToken.Ip != token.Ip.String() {
-				err = AccessDeniedError
-			}
+			violated = refreshToken.Ip != token.Ip.String()
 		case config.TokenRefreshFieldFingerprint:
-			if refreshToken.Fingerprint != token.Fingerprint {
-				err = AccessDeniedError
-			}
+			violated = refreshToken.Fingerprint != token.Fingerprint
 		case config.TokenRefreshFieldUserAgent:
-			if refreshToken.UserAgent != token.UserAgent {
-				err = AccessDeniedError
-			}
+			violated = refreshToken.UserAgent != token.UserAgent
+		}
+
+		if violated {
+			err = AccessDeniedError
+
+			service.metrics.RefreshDenials.WithLabelValues(fieldForCheck).Inc()
+
+			event := service.event(domain.EventRefreshViolation, token)
+			event.Login, event.Session, event.Field = refreshToken.Login, refreshToken.UUID, fieldForCheck
+
+			violations = append(violations, event)
 		}
 	}
 	if err != nil {
-		switch service.config.AccessViolation {
+		service.emit(ctx, violations...)
+
+		switch policy.AccessViolation {
 		case config.TokensAccessViolationActionDisableAll:
-			if err := service.DisableAll(ctx, refreshToken.Login); err != nil {
+			if err := service.DisableAll(ctx, repository.RevokeReasonViolation, refreshToken.Login); err != nil {
 				return nil, "", err
 			}
 		case config.TokensAccessViolationActionDisableCurrent:
-			if err := service.blocker.BlockByUUID(ctx, refreshToken.UUID); err != nil {
+			if err := service.block(ctx, repository.RevokeReasonViolation, refreshToken); err != nil {
 				return nil, "", err
 			}
 		}
@@ -140,16 +188,17 @@ func (service *token) Refresh(ctx context.Context, token *domain.RefreshToken) (
 		return nil, "", err
 	}
 
-	if err := service.blocker.BlockByUUID(ctx, refreshToken.UUID); err != nil {
+	if err := service.block(ctx, repository.RevokeReasonRotated, refreshToken); err != nil {
 		return nil, "", err
 	}
 
 	token.Login = refreshToken.Login
 
-	return service.generate(ctx, token)
+	return service.generate(ctx, token, domain.EventSessionRefreshed, &refreshToken.UUID)
 }
 
-func (service *token) generate(ctx context.Context, token *domain.RefreshToken) (*domain.RefreshToken, string, error) {
+// generate creating token, the event of session is written to outbox together with token
+func (service *token) generate(ctx context.Context, token *domain.RefreshToken, eventType string, previous *uuid.UUID) (*domain.RefreshToken, string, error) {
 	ctx, span := service.tracer.Start(ctx, "service.token.generate")
 	defer span.End()
 
@@ -160,8 +209,19 @@ func (service *token) generate(ctx context.Context, token *domain.RefreshToken)
 		return nil, "", err
 	}
 
+	event := service.event(eventType, token)
+	event.Previous = previous
+
+	ctx, err = service.events.Attach(ctx, event)
+	if err != nil {
+		return nil, "", err
+	}
+
+	policy := service.policy.Get()
 	now := time.Now().In(time.UTC)
 
+	token.ExpiresIn = now.Add(policy.RefreshLifetime)
+
 	err = service.saver.Insert(ctx, &repository.RefreshToken{
 		UUID:        token.UUID,
 		Login:       token.Login,
@@ -169,18 +229,27 @@ func (service *token) generate(ctx context.Context, token *domain.RefreshToken)
 		Fingerprint: token.Fingerprint,
 		UserAgent:   token.UserAgent,
 		CreatedAt:   now,
-		ExpiresIn:   now.Add(service.config.RefreshLifetime),
+		ExpiresIn:   token.ExpiresIn,
 	})
 	if err != nil {
 		return nil, "", err
 	}
 
-	jsonToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+	key, err := service.keyring.Signing()
+	if err != nil {
+		return nil, "", err
+	}
+
+	jsonToken := jwt.NewWithClaims(key.Method(), jwt.MapClaims{
 		LoginJwtFieldName:     token.Login.String(),
-		ExpiresInJwtFieldName: now.Add(service.config.AccessLifetime).Unix(),
+		ExpiresInJwtFieldName: now.Add(policy.AccessLifetime).Unix(),
 	})
 
-	jwt, err := jsonToken.SignedString(service.secret)
+	if key.ID != "" {
+		jsonToken.Header[KeyIdJwtHeaderName] = key.ID
+	}
+
+	jwt, err := jsonToken.SignedString(key.SignKey())
 	if err != nil {
 		return nil, "", err
 	}
@@ -188,7 +257,7 @@ func (service *token) generate(ctx context.Context, token *domain.RefreshToken)
 	return token, jwt, nil
 }
 
-func (service *token) DisableAll(ctx context.Context, login uuid.UUID, exclude ...uuid.UUID) error {
+func (service *token) DisableAll(ctx context.Context, reason repository.RevokeReason, login uuid.UUID, exclude ...uuid.UUID) error {
 	ctx, span := service.tracer.Start(ctx, "service.token.disable.all")
 	defer span.End()
 
@@ -210,19 +279,122 @@ func (service *token) DisableAll(ctx context.Context, login uuid.UUID, exclude .
 		tokens = append(tokens[:index], tokens[index+1:]...)
 	}
 
-	uuids := make([]uuid.UUID, 0, len(tokens))
+	return service.block(ctx, reason, tokens...)
+}
+
+func (service *token) Sessions(ctx context.Context, login uuid.UUID) ([]*domain.Session, error) {
+	ctx, span := service.tracer.Start(ctx, "service.token.sessions")
+	defer span.End()
+
+	tokens, err := service.finder.FindAllByLogin(ctx, login)
+	if err != nil && err != db.RecordNotFoundError {
+		return nil, err
+	}
+
+	sessions := make([]*domain.Session, 0, len(tokens))
 	for _, token := range tokens {
-		uuids = append(uuids, token.UUID)
+		sessions = append(sessions, &domain.Session{
+			UUID:         token.UUID,
+			Fingerprint:  token.Fingerprint,
+			Ip:           token.Ip,
+			UserAgent:    token.UserAgent,
+			CreatedAt:    token.CreatedAt,
+			ExpiresIn:    token.ExpiresIn,
+			RevokedAt:    token.RevokedAt,
+			RevokeReason: string(token.RevokeReason),
+		})
 	}
 
-	return service.blocker.BlockByUUID(ctx, uuids...)
+	return sessions, nil
 }
 
-func (service *token) Disable(ctx context.Context, uuid uuid.UUID) error {
+func (service *token) Disable(ctx context.Context, reason repository.RevokeReason, uuid uuid.UUID) error {
 	ctx, span := service.tracer.Start(ctx, "service.token.disable")
 	defer span.End()
 
-	return service.blocker.BlockByUUID(ctx, uuid)
+	refreshToken, err := service.finder.FindByUUID(ctx, uuid)
+	if err != nil && err != db.RecordNotFoundError {
+		return err
+	}
+
+	if err == db.RecordNotFoundError {
+		return service.blocker.BlockByUUID(ctx, reason, uuid)
+	}
+
+	return service.block(ctx, reason, refreshToken)
+}
+
+// block blocking tokens by reason, the event of revoke for every token is written to outbox together with block
+func (service *token) block(ctx context.Context, reason repository.RevokeReason, tokens ...*repository.RefreshToken) error {
+	uuids := make([]uuid.UUID, 0, len(tokens))
+	events := make([]*domain.Event, 0, len(tokens))
+
+	for _, token := range tokens {
+		uuids = append(uuids, token.UUID)
+		events = append(events, &domain.Event{
+			Type:      domain.EventSessionRevoked,
+			Login:     token.Login,
+			Session:   token.UUID,
+			Reason:    string(reason),
+			Ip:        token.Ip,
+			UserAgent: token.UserAgent,
+		})
+	}
+
+	ctx, err := service.events.Attach(ctx, events...)
+	if err != nil {
+		return err
+	}
+
+	return service.blocker.BlockByUUID(ctx, reason, uuids...)
+}
+
+// detectReuse emitting event of reuse when refresh is made by token which was already rotated
+func (service *token) detectReuse(ctx context.Context, token *domain.RefreshToken) error {
+	revokedToken, err := service.finder.FindRevokedByUUID(ctx, token.UUID)
+	if err == db.RecordNotFoundError {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if revokedToken.RevokeReason != repository.RevokeReasonRotated {
+		return nil
+	}
+
+	service.logger.Warnf("token.service: reuse of rotated token '%s' of login '%s'", revokedToken.UUID, revokedToken.Login)
+
+	event := service.event(domain.EventRefreshReuseDetected, token)
+	event.Login = revokedToken.Login
+
+	service.emit(ctx, event)
+
+	return nil
+}
+
+// event creating event about session of token
+func (service *token) event(eventType string, token *domain.RefreshToken) *domain.Event {
+	event := &domain.Event{
+		Type:      eventType,
+		Login:     token.Login,
+		Session:   token.UUID,
+		UserAgent: token.UserAgent,
+	}
+
+	if token.Ip != nil {
+		event.Ip = token.Ip.String()
+	}
+
+	return event
+}
+
+// emit adding events to outbox, an error of outbox is not an error of operation with token
+func (service *token) emit(ctx context.Context, events ...*domain.Event) {
+	if err := service.events.Emit(ctx, events...); err != nil {
+		service.logger.Errorf("token.service: events not emitted - %s", err)
+	}
 }
 
 func (service *token) Validation(ctx context.Context, token string) error {
@@ -245,11 +417,16 @@ func (service *token) Parse(ctx context.Context, token string) (*domain.JwtClaim
 	ctx, span := service.tracer.Start(ctx, "service.token.parse")
 	defer span.End()
 
+	// claims of token with invalid signature or expired are not trusted
 	jwtToken, err := service.parse(ctx, token)
-	if err != nil && jwtToken == nil {
+	if err != nil {
 		return nil, err
 	}
 
+	if !jwtToken.Valid {
+		return nil, AccessDeniedError
+	}
+
 	claims, ok := jwtToken.Claims.(jwt.MapClaims)
 	if !ok {
 		return nil, errors.New(fmt.Sprintf("jwtToken.Claims: not conversion to jwt.MapClaims, type '%T'", jwtToken.Claims))
@@ -290,10 +467,17 @@ func (service *token) parse(ctx context.Context, token string) (*jwt.Token, erro
 	defer span.End()
 
 	return service.parser.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		id, _ := token.Header[KeyIdJwtHeaderName].(string)
+
+		key, err := service.keyring.Verifying(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if token.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 
-		return service.secret, nil
+		return key.VerifyKey(), nil
 	})
 }
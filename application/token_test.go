@@ -0,0 +1,336 @@
+package application
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/domain"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/keyring"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/google/uuid"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestService creating Token over repository with keyring of static secret, events are stored for destination 'test'
+func newTestService(tokenRepository repository.Repository, tokenConfig *config.Token) Token {
+	events := NewEvents(tokenRepository.(repository.Outbox), []string{"test"}, testTracer)
+
+	return NewToken(
+		NewPolicy(tokenConfig),
+		testLogger(),
+		tokenRepository,
+		tokenRepository,
+		tokenRepository,
+		events,
+		keyring.NewSecret("0123456789abcdef0123456789abcdef"),
+		testMetrics,
+		testTracer,
+	)
+}
+
+// newTestSession domain token of login from the same client
+func newTestSession(login uuid.UUID) *domain.RefreshToken {
+	return &domain.RefreshToken{
+		Login:       login,
+		Ip:          net.ParseIP("127.0.0.1"),
+		Fingerprint: "fingerprint",
+		UserAgent:   "test",
+	}
+}
+
+// eventTypes return types of pending events of outbox
+func eventTypes(t *testing.T, outbox repository.Outbox) map[string]int {
+	t.Helper()
+
+	types := map[string]int{}
+	for _, event := range pending(t, outbox) {
+		types[event.Type]++
+	}
+
+	return types
+}
+
+func TestCreateSignsAccessToken(t *testing.T) {
+	ctx := context.Background()
+	tokenRepository := repository.NewMemory(testTracer)
+	service := newTestService(tokenRepository, testTokenConfig())
+
+	login := uuid.New()
+
+	created, accessToken, err := service.Create(ctx, newTestSession(login))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Validation(ctx, accessToken); err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := service.Parse(ctx, accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Login != login {
+		t.Fatalf("access token of login '%s', want '%s'", claims.Login, login)
+	}
+
+	if _, err := tokenRepository.FindByUUID(ctx, created.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	if types := eventTypes(t, tokenRepository.(repository.Outbox)); types[domain.EventSessionCreated] != 1 {
+		t.Fatalf("events %v, want one '%s'", types, domain.EventSessionCreated)
+	}
+}
+
+func TestValidationDeniesForeignToken(t *testing.T) {
+	ctx := context.Background()
+
+	_, accessToken, err := newTestService(repository.NewMemory(testTracer), testTokenConfig()).Create(ctx, newTestSession(uuid.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	foreign := NewToken(
+		NewPolicy(testTokenConfig()),
+		testLogger(),
+		repository.NewMemory(testTracer),
+		repository.NewMemory(testTracer),
+		repository.NewMemory(testTracer),
+		NewEvents(nil, nil, testTracer),
+		keyring.NewSecret("fedcba9876543210fedcba9876543210"),
+		testMetrics,
+		testTracer,
+	)
+
+	for _, token := range []string{accessToken, "not a token"} {
+		if err := foreign.Validation(ctx, token); err == nil {
+			t.Fatalf("token '%s' is valid for foreign secret", token)
+		}
+	}
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	ctx := context.Background()
+	tokenRepository := repository.NewMemory(testTracer)
+	service := newTestService(tokenRepository, testTokenConfig())
+
+	login := uuid.New()
+
+	created, _, err := service.Create(ctx, newTestSession(login))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refresh := newTestSession(uuid.Nil)
+	refresh.UUID = created.UUID
+
+	refreshed, _, err := service.Refresh(ctx, refresh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if refreshed.UUID == created.UUID || refreshed.Login != login {
+		t.Fatalf("refreshed token '%s' of login '%s', want new token of '%s'", refreshed.UUID, refreshed.Login, login)
+	}
+
+	revoked, err := tokenRepository.FindRevokedByUUID(ctx, created.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if revoked.RevokeReason != repository.RevokeReasonRotated {
+		t.Fatalf("previous token revoked by '%s', want '%s'", revoked.RevokeReason, repository.RevokeReasonRotated)
+	}
+
+	// the second refresh by rotated token is reuse
+	reuse := newTestSession(uuid.Nil)
+	reuse.UUID = created.UUID
+
+	if _, _, err := service.Refresh(ctx, reuse); err != AccessDeniedError {
+		t.Fatalf("refresh by rotated token, error %v, want AccessDeniedError", err)
+	}
+
+	types := eventTypes(t, tokenRepository.(repository.Outbox))
+	if types[domain.EventSessionRefreshed] != 1 || types[domain.EventRefreshReuseDetected] != 1 {
+		t.Fatalf("events %v, want refresh and reuse", types)
+	}
+}
+
+func TestRefreshViolation(t *testing.T) {
+	for action, revoked := range map[string]int{
+		config.TokensAccessViolationActionDisableAll:     2,
+		config.TokensAccessViolationActionDisableCurrent: 1,
+		config.TokensAccessViolationActionNone:           0,
+	} {
+		action, revoked := action, revoked
+
+		t.Run(action, func(t *testing.T) {
+			ctx := context.Background()
+			tokenRepository := repository.NewMemory(testTracer)
+
+			tokenConfig := testTokenConfig()
+			tokenConfig.AccessViolation = action
+
+			service := newTestService(tokenRepository, tokenConfig)
+
+			login := uuid.New()
+
+			created, _, err := service.Create(ctx, newTestSession(login))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err := service.Create(ctx, newTestSession(login)); err != nil {
+				t.Fatal(err)
+			}
+
+			refresh := newTestSession(uuid.Nil)
+			refresh.UUID = created.UUID
+			refresh.Fingerprint = "other"
+
+			if _, _, err := service.Refresh(ctx, refresh); err != AccessDeniedError {
+				t.Fatalf("refresh from other client, error %v, want AccessDeniedError", err)
+			}
+
+			actual, err := tokenRepository.FindByLogin(ctx, login)
+			if err != nil && err != db.RecordNotFoundError {
+				t.Fatal(err)
+			}
+
+			if len(actual) != 2-revoked {
+				t.Fatalf("%d tokens are kept, want %d", len(actual), 2-revoked)
+			}
+
+			if types := eventTypes(t, tokenRepository.(repository.Outbox)); types[domain.EventRefreshViolation] != 1 {
+				t.Fatalf("events %v, want one '%s'", types, domain.EventRefreshViolation)
+			}
+		})
+	}
+}
+
+func TestRefreshDeniesExpiredToken(t *testing.T) {
+	ctx := context.Background()
+	tokenRepository := repository.NewMemory(testTracer)
+
+	token := newTestToken(uuid.New())
+	token.ExpiresIn = time.Now().In(time.UTC).Add(-time.Second)
+
+	if err := tokenRepository.Insert(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+
+	refresh := newTestSession(uuid.Nil)
+	refresh.UUID = token.UUID
+
+	if _, _, err := newTestService(tokenRepository, testTokenConfig()).Refresh(ctx, refresh); err != AccessDeniedError {
+		t.Fatalf("refresh by expired token, error %v, want AccessDeniedError", err)
+	}
+
+	revoked, err := tokenRepository.FindRevokedByUUID(ctx, token.UUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if revoked.RevokeReason != repository.RevokeReasonExpired {
+		t.Fatalf("expired token revoked by '%s', want '%s'", revoked.RevokeReason, repository.RevokeReasonExpired)
+	}
+}
+
+func TestDisableAllExcludesCurrent(t *testing.T) {
+	ctx := context.Background()
+	tokenRepository := repository.NewMemory(testTracer)
+	service := newTestService(tokenRepository, testTokenConfig())
+
+	login := uuid.New()
+
+	current, _, err := service.Create(ctx, newTestSession(login))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := service.Create(ctx, newTestSession(login)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.DisableAll(ctx, repository.RevokeReasonLogout, login, current.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := tokenRepository.FindByLogin(ctx, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actual) != 1 || actual[0].UUID != current.UUID {
+		t.Fatalf("tokens %v are kept, want only current '%s'", actual, current.UUID)
+	}
+
+	sessions, err := service.Sessions(ctx, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sessions) != 2 {
+		t.Fatalf("%d sessions, want 2 including revoked", len(sessions))
+	}
+
+	// the unknown token is blocked without error
+	if err := service.Disable(ctx, repository.RevokeReasonLogout, uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateRevokesOldestTokensOverMaximum(t *testing.T) {
+	ctx := context.Background()
+	tokenRepository := repository.NewMemory(testTracer)
+
+	tokenConfig := testTokenConfig()
+	tokenConfig.MaximumTokens = 2
+
+	login := uuid.New()
+
+	// the repository sets time of creating on insert, so the tokens are created in order of inserting
+	tokens := make([]*repository.RefreshToken, 4)
+	for index := range tokens {
+		tokens[index] = newTestToken(login)
+
+		if err := tokenRepository.Insert(ctx, tokens[index]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	created, _, err := newTestService(tokenRepository, tokenConfig).Create(ctx, newTestSession(login))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := tokenRepository.FindByLogin(ctx, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(actual) != int(tokenConfig.MaximumTokens) {
+		t.Fatalf("expected %d tokens of login, got %d", tokenConfig.MaximumTokens, len(actual))
+	}
+
+	for _, token := range actual {
+		if token.UUID != created.UUID && token.UUID != tokens[len(tokens)-1].UUID {
+			t.Fatalf("token '%s' is kept, expected the newest and created tokens", token.UUID)
+		}
+	}
+
+	for _, token := range tokens[:len(tokens)-1] {
+		revoked, err := tokenRepository.FindRevokedByUUID(ctx, token.UUID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if revoked.RevokeReason != repository.RevokeReasonLimit {
+			t.Fatalf("token '%s' revoked by '%s', expected '%s'", token.UUID, revoked.RevokeReason, repository.RevokeReasonLimit)
+		}
+	}
+}
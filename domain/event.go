@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	EventSessionCreated       = "session.created"
+	EventSessionRefreshed     = "session.refreshed"
+	EventSessionRevoked       = "session.revoked"
+	EventRefreshViolation     = "refresh.violation"
+	EventRefreshReuseDetected = "refresh.reuse_detected"
+)
+
+// Event security event of session
+type Event struct {
+	UUID    uuid.UUID `json:"uuid"`
+	Type    string    `json:"type"`
+	Login   uuid.UUID `json:"login"`
+	Session uuid.UUID `json:"session"`
+	// Previous session replaced by the session on refresh
+	Previous *uuid.UUID `json:"previous,omitempty"`
+	// Reason reason of revoke
+	Reason string `json:"reason,omitempty"`
+	// Field field of refresh check which is violated
+	Field     string    `json:"field,omitempty"`
+	Ip        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
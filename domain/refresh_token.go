@@ -3,6 +3,7 @@ package domain
 import (
 	"github.com/google/uuid"
 	"net"
+	"time"
 )
 
 type RefreshToken struct {
@@ -11,4 +12,5 @@ type RefreshToken struct {
 	Ip          net.IP
 	Fingerprint string
 	UserAgent   string
+	ExpiresIn   time.Time
 }
@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Session refresh token of login, revoked session has time and reason of revoke
+type Session struct {
+	UUID         uuid.UUID
+	Fingerprint  string
+	Ip           string
+	UserAgent    string
+	CreatedAt    time.Time
+	ExpiresIn    time.Time
+	RevokedAt    *time.Time
+	RevokeReason string
+}
@@ -0,0 +1,19 @@
+package broker
+
+import "context"
+
+const (
+	RedisBroker = "redis"
+)
+
+// Handler receiving payload of message published to topic
+type Handler func(topic string, payload []byte)
+
+// Broker publish/subscribe channel shared by replicas of application
+type Broker interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+
+	// Subscribe calling handler for messages of topics until ctx is done, subscribed is called once
+	// the subscription is active
+	Subscribe(ctx context.Context, subscribed func(), handler Handler, topics ...string) error
+}
@@ -0,0 +1,83 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	memorySubscriberCap = 1000
+)
+
+type memorySubscriber struct {
+	topics   map[string]bool
+	messages chan memoryMessage
+}
+
+type memoryMessage struct {
+	topic   string
+	payload []byte
+}
+
+type memory struct {
+	rwMutex *sync.RWMutex
+
+	subscribers map[*memorySubscriber]bool
+}
+
+// NewMemory creating in-process Broker, it's a stand-in for a shared broker in tests, it does not share messages between replicas
+func NewMemory() Broker {
+	return &memory{rwMutex: &sync.RWMutex{}, subscribers: map[*memorySubscriber]bool{}}
+}
+
+func (broker *memory) Publish(ctx context.Context, topic string, payload []byte) error {
+	broker.rwMutex.RLock()
+	defer broker.rwMutex.RUnlock()
+
+	for subscriber := range broker.subscribers {
+		if !subscriber.topics[topic] {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case subscriber.messages <- memoryMessage{topic: topic, payload: payload}:
+		}
+	}
+
+	return nil
+}
+
+func (broker *memory) Subscribe(ctx context.Context, subscribed func(), handler Handler, topics ...string) error {
+	subscriber := &memorySubscriber{
+		topics:   map[string]bool{},
+		messages: make(chan memoryMessage, memorySubscriberCap),
+	}
+
+	for _, topic := range topics {
+		subscriber.topics[topic] = true
+	}
+
+	broker.rwMutex.Lock()
+	broker.subscribers[subscriber] = true
+	broker.rwMutex.Unlock()
+
+	defer func() {
+		broker.rwMutex.Lock()
+		defer broker.rwMutex.Unlock()
+
+		delete(broker.subscribers, subscriber)
+	}()
+
+	subscribed()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message := <-subscriber.messages:
+			handler(message.topic, message.payload)
+		}
+	}
+}
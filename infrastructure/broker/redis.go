@@ -0,0 +1,45 @@
+package broker
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+)
+
+type redisBroker struct {
+	client *redis.Client
+}
+
+// NewRedis creating Broker on top of redis pub/sub
+func NewRedis(client *redis.Client) Broker {
+	return &redisBroker{client: client}
+}
+
+func (broker *redisBroker) Publish(ctx context.Context, topic string, payload []byte) error {
+	return broker.client.Publish(ctx, topic, payload).Err()
+}
+
+func (broker *redisBroker) Subscribe(ctx context.Context, subscribed func(), handler Handler, topics ...string) error {
+	pubSub := broker.client.Subscribe(ctx, topics...)
+	defer pubSub.Close()
+
+	if _, err := pubSub.Receive(ctx); err != nil {
+		return err
+	}
+
+	subscribed()
+
+	messages := pubSub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+
+			handler(message.Channel, []byte(message.Payload))
+		}
+	}
+}
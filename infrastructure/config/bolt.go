@@ -0,0 +1,36 @@
+package config
+
+import (
+	"github.com/diez37/go-packages/configurator"
+	"time"
+)
+
+const (
+	BoltPathFieldName    = "db.bolt.path"
+	BoltTimeoutFieldName = "db.bolt.timeout"
+
+	BoltPathDefault    = "./tokenizer.db"
+	BoltTimeoutDefault = 5 * time.Second
+)
+
+type Bolt struct {
+	Path    string
+	Timeout time.Duration
+}
+
+func NewBolt() *Bolt {
+	return &Bolt{}
+}
+
+func (config *Bolt) Configure(configurator configurator.Configurator) {
+	configurator.SetDefault(BoltPathFieldName, BoltPathDefault)
+	configurator.SetDefault(BoltTimeoutFieldName, BoltTimeoutDefault)
+
+	if path := configurator.GetString(BoltPathFieldName); config.Path == "" || config.Path == BoltPathDefault {
+		config.Path = path
+	}
+
+	if timeout := configurator.GetDuration(BoltTimeoutFieldName); config.Timeout == 0 || config.Timeout == BoltTimeoutDefault {
+		config.Timeout = timeout
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/diez37/go-packages/configurator"
+	"go.uber.org/multierr"
+	"net/http"
+	"strings"
+)
+
+const (
+	CookieSameSiteStrict = "strict"
+	CookieSameSiteLax    = "lax"
+	CookieSameSiteNone   = "none"
+
+	// CookieHostPrefix prefix of name of cookie which browser accepts only with Secure, Path '/' and without Domain
+	CookieHostPrefix = "__Host-"
+
+	CookieNameFieldName       = "tokens.cookie.name"
+	CookiePathFieldName       = "tokens.cookie.path"
+	CookieDomainFieldName     = "tokens.cookie.domain"
+	CookieSecureFieldName     = "tokens.cookie.secure"
+	CookieHttpOnlyFieldName   = "tokens.cookie.http_only"
+	CookieSameSiteFieldName   = "tokens.cookie.same_site"
+	CookieHostPrefixFieldName = "tokens.cookie.host_prefix"
+
+	CookieNameDefault       = "token"
+	CookiePathDefault       = "/"
+	CookieSecureDefault     = true
+	CookieHttpOnlyDefault   = true
+	CookieSameSiteDefault   = CookieSameSiteStrict
+	CookieHostPrefixDefault = true
+)
+
+var (
+	CookieSameSites = []string{CookieSameSiteStrict, CookieSameSiteLax, CookieSameSiteNone}
+)
+
+// Cookie attributes of cookie of refresh token, the defaults are accepted only by HTTPS
+type Cookie struct {
+	// Name name of cookie without prefix
+	Name string
+	// Path path of cookie, the browser sends cookie only to paths under it
+	Path string
+	// Domain domain of cookie shared with subdomains, empty value limits cookie to host of request
+	Domain   string
+	Secure   bool
+	HttpOnly bool
+	// SameSite one of 'strict', 'lax' or 'none', 'none' requires Secure
+	SameSite string
+	// HostPrefix adding prefix '__Host-' to Name, it requires Secure, Path '/' and empty Domain
+	HostPrefix bool
+}
+
+func NewCookie() *Cookie {
+	return &Cookie{}
+}
+
+func (config *Cookie) Configure(configurator configurator.Configurator) {
+	configurator.SetDefault(CookieNameFieldName, CookieNameDefault)
+	configurator.SetDefault(CookiePathFieldName, CookiePathDefault)
+	configurator.SetDefault(CookieSecureFieldName, CookieSecureDefault)
+	configurator.SetDefault(CookieHttpOnlyFieldName, CookieHttpOnlyDefault)
+	configurator.SetDefault(CookieSameSiteFieldName, CookieSameSiteDefault)
+	configurator.SetDefault(CookieHostPrefixFieldName, CookieHostPrefixDefault)
+
+	if name := configurator.GetString(CookieNameFieldName); config.Name == "" || config.Name == CookieNameDefault {
+		config.Name = name
+	}
+
+	if path := configurator.GetString(CookiePathFieldName); config.Path == "" || config.Path == CookiePathDefault {
+		config.Path = path
+	}
+
+	if domain := configurator.GetString(CookieDomainFieldName); config.Domain == "" {
+		config.Domain = domain
+	}
+
+	if secure := configurator.GetBool(CookieSecureFieldName); config.Secure {
+		config.Secure = secure
+	}
+
+	if httpOnly := configurator.GetBool(CookieHttpOnlyFieldName); config.HttpOnly {
+		config.HttpOnly = httpOnly
+	}
+
+	if sameSite := configurator.GetString(CookieSameSiteFieldName); config.SameSite == "" || config.SameSite == CookieSameSiteDefault {
+		config.SameSite = sameSite
+	}
+
+	if hostPrefix := configurator.GetBool(CookieHostPrefixFieldName); config.HostPrefix {
+		config.HostPrefix = hostPrefix
+	}
+}
+
+// Validate checking attributes of cookie, the error lists every combination which browser refuses
+func (config *Cookie) Validate() error {
+	var err error
+
+	if config.Name == "" {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' is required", CookieNameFieldName)))
+	}
+
+	if !strings.HasPrefix(config.Path, "/") {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must start with '/', got '%s'", CookiePathFieldName, config.Path)))
+	}
+
+	if !contains(CookieSameSites, config.SameSite) {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' has unknown value '%s', availably [%s]",
+			CookieSameSiteFieldName, config.SameSite, strings.Join(CookieSameSites, ","),
+		)))
+	}
+
+	if config.SameSite == CookieSameSiteNone && !config.Secure {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' '%s' requires '%s'", CookieSameSiteFieldName, CookieSameSiteNone, CookieSecureFieldName)))
+	}
+
+	if config.HostPrefix && (!config.Secure || config.Path != "/" || config.Domain != "") {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' requires '%s', '%s' '/' and empty '%s', disable the prefix for shared domain",
+			CookieHostPrefixFieldName, CookieSecureFieldName, CookiePathFieldName, CookieDomainFieldName,
+		)))
+	}
+
+	return err
+}
+
+// FullName return name of cookie with prefix
+func (config *Cookie) FullName() string {
+	if config.HostPrefix {
+		return CookieHostPrefix + config.Name
+	}
+
+	return config.Name
+}
+
+// Cookie creating cookie of value with configured attributes
+func (config *Cookie) Cookie(value string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     config.FullName(),
+		Value:    value,
+		Path:     config.Path,
+		Domain:   config.Domain,
+		Secure:   config.Secure,
+		HttpOnly: config.HttpOnly,
+	}
+
+	switch config.SameSite {
+	case CookieSameSiteStrict:
+		cookie.SameSite = http.SameSiteStrictMode
+	case CookieSameSiteLax:
+		cookie.SameSite = http.SameSiteLaxMode
+	case CookieSameSiteNone:
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+
+	return cookie
+}
+
+func (config *Cookie) Settings() map[string]interface{} {
+	return map[string]interface{}{
+		CookieNameFieldName:       config.Name,
+		CookiePathFieldName:       config.Path,
+		CookieDomainFieldName:     config.Domain,
+		CookieSecureFieldName:     config.Secure,
+		CookieHttpOnlyFieldName:   config.HttpOnly,
+		CookieSameSiteFieldName:   config.SameSite,
+		CookieHostPrefixFieldName: config.HostPrefix,
+	}
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/diez37/go-packages/configurator"
+	"go.uber.org/multierr"
+	"net/url"
+	"time"
+)
+
+const (
+	EventsWebhooksFieldName       = "events.webhooks"
+	EventsPublishersFieldName     = "events.publishers"
+	EventsNatsUrlFieldName        = "events.nats.url"
+	EventsNatsSubjectFieldName    = "events.nats.subject"
+	EventsKafkaBrokersFieldName   = "events.kafka.brokers"
+	EventsKafkaTopicFieldName     = "events.kafka.topic"
+	EventsSecretFieldName         = "events.secret"
+	EventsDelayFieldName          = "events.delay"
+	EventsBatchFieldName          = "events.batch"
+	EventsAttemptsFieldName       = "events.attempts"
+	EventsBackoffInitialFieldName = "events.backoff.initial"
+	EventsBackoffMaximumFieldName = "events.backoff.maximum"
+	EventsTimeoutFieldName        = "events.timeout"
+
+	EventsNatsUrlDefault        = "nats://127.0.0.1:4222"
+	EventsNatsSubjectDefault    = "tokenizer.events"
+	EventsKafkaBrokersDefault   = "127.0.0.1:9092"
+	EventsKafkaTopicDefault     = "tokenizer.events"
+	EventsDelayDefault          = time.Second
+	EventsBatchDefault          = uint(100)
+	EventsAttemptsDefault       = uint(10)
+	EventsBackoffInitialDefault = time.Second
+	EventsBackoffMaximumDefault = 10 * time.Minute
+	EventsTimeoutDefault        = 5 * time.Second
+)
+
+type Events struct {
+	// Webhooks urls which receive every event, empty value disabled webhooks
+	Webhooks []string
+	// Secret key of HMAC-SHA256 signature of webhook requests
+	Secret string
+
+	// Publishers names of sinks which receive every event in addition to webhooks
+	Publishers []string
+	// NatsUrl and NatsSubject server of publisher 'nats' and prefix of subject, subject of event is 'prefix.type'
+	NatsUrl     string
+	NatsSubject string
+	// KafkaBrokers and KafkaTopic brokers and topic of publisher 'kafka'
+	KafkaBrokers []string
+	KafkaTopic   string
+
+	// Delay delay between deliveries of outbox
+	Delay time.Duration
+	// Batch maximum number of events in one delivery
+	Batch uint
+	// Attempts number of attempts of delivery, after the last one event is dropped
+	Attempts uint
+	// BackoffInitial and BackoffMaximum delay before the first retry, it doubles for every next retry up to maximum
+	BackoffInitial time.Duration
+	BackoffMaximum time.Duration
+	// Timeout timeout of one webhook request
+	Timeout time.Duration
+}
+
+func NewEvents() *Events {
+	return &Events{}
+}
+
+func (config *Events) Configure(configurator configurator.Configurator) {
+	configurator.SetDefault(EventsNatsUrlFieldName, EventsNatsUrlDefault)
+	configurator.SetDefault(EventsNatsSubjectFieldName, EventsNatsSubjectDefault)
+	configurator.SetDefault(EventsKafkaBrokersFieldName, []string{EventsKafkaBrokersDefault})
+	configurator.SetDefault(EventsKafkaTopicFieldName, EventsKafkaTopicDefault)
+	configurator.SetDefault(EventsDelayFieldName, EventsDelayDefault)
+	configurator.SetDefault(EventsBatchFieldName, EventsBatchDefault)
+	configurator.SetDefault(EventsAttemptsFieldName, EventsAttemptsDefault)
+	configurator.SetDefault(EventsBackoffInitialFieldName, EventsBackoffInitialDefault)
+	configurator.SetDefault(EventsBackoffMaximumFieldName, EventsBackoffMaximumDefault)
+	configurator.SetDefault(EventsTimeoutFieldName, EventsTimeoutDefault)
+
+	if webhooks := configurator.GetStringSlice(EventsWebhooksFieldName); len(config.Webhooks) == 0 {
+		config.Webhooks = webhooks
+	}
+
+	if secret := configurator.GetString(EventsSecretFieldName); config.Secret == "" {
+		config.Secret = secret
+	}
+
+	if publishers := configurator.GetStringSlice(EventsPublishersFieldName); len(config.Publishers) == 0 {
+		config.Publishers = publishers
+	}
+
+	if url := configurator.GetString(EventsNatsUrlFieldName); config.NatsUrl == "" || config.NatsUrl == EventsNatsUrlDefault {
+		config.NatsUrl = url
+	}
+
+	if subject := configurator.GetString(EventsNatsSubjectFieldName); config.NatsSubject == "" || config.NatsSubject == EventsNatsSubjectDefault {
+		config.NatsSubject = subject
+	}
+
+	if brokers := configurator.GetStringSlice(EventsKafkaBrokersFieldName); len(config.KafkaBrokers) == 0 {
+		config.KafkaBrokers = brokers
+	}
+
+	if topic := configurator.GetString(EventsKafkaTopicFieldName); config.KafkaTopic == "" || config.KafkaTopic == EventsKafkaTopicDefault {
+		config.KafkaTopic = topic
+	}
+
+	if delay := configurator.GetDuration(EventsDelayFieldName); config.Delay == 0 || config.Delay == EventsDelayDefault {
+		config.Delay = delay
+	}
+
+	if batch := configurator.GetUint(EventsBatchFieldName); config.Batch == 0 || config.Batch == EventsBatchDefault {
+		config.Batch = batch
+	}
+
+	if attempts := configurator.GetUint(EventsAttemptsFieldName); config.Attempts == 0 || config.Attempts == EventsAttemptsDefault {
+		config.Attempts = attempts
+	}
+
+	if backoff := configurator.GetDuration(EventsBackoffInitialFieldName); config.BackoffInitial == 0 || config.BackoffInitial == EventsBackoffInitialDefault {
+		config.BackoffInitial = backoff
+	}
+
+	if backoff := configurator.GetDuration(EventsBackoffMaximumFieldName); config.BackoffMaximum == 0 || config.BackoffMaximum == EventsBackoffMaximumDefault {
+		config.BackoffMaximum = backoff
+	}
+
+	if timeout := configurator.GetDuration(EventsTimeoutFieldName); config.Timeout == 0 || config.Timeout == EventsTimeoutDefault {
+		config.Timeout = timeout
+	}
+}
+
+// Destinations return destinations of events, urls of webhooks and names of publishers
+func (config *Events) Destinations() []string {
+	destinations := make([]string, 0, len(config.Webhooks)+len(config.Publishers))
+
+	return append(append(destinations, config.Webhooks...), config.Publishers...)
+}
+
+// Validate checking configuration of events, the webhooks without secret are refused by profiles except dev
+func (config *Events) Validate(profile *Profile) error {
+	var err error
+
+	for _, webhook := range config.Webhooks {
+		if parsed, parseErr := url.Parse(webhook); parseErr != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' has invalid url '%s', http or https url is expected", EventsWebhooksFieldName, webhook)))
+		}
+	}
+
+	if len(config.Webhooks) > 0 && config.Secret == "" && !profile.IsDev() {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' is required with '%s' by profile '%s'", EventsSecretFieldName, EventsWebhooksFieldName, profile.Name)))
+	}
+
+	if config.Attempts == 0 {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must be at least 1", EventsAttemptsFieldName)))
+	}
+
+	if config.Batch == 0 {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must be at least 1", EventsBatchFieldName)))
+	}
+
+	delays := []struct {
+		name  string
+		delay time.Duration
+	}{
+		{name: EventsDelayFieldName, delay: config.Delay},
+		{name: EventsBackoffInitialFieldName, delay: config.BackoffInitial},
+		{name: EventsTimeoutFieldName, delay: config.Timeout},
+	}
+
+	for _, delay := range delays {
+		if delay.delay <= 0 {
+			err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must be positive, got '%s'", delay.name, delay.delay)))
+		}
+	}
+
+	if config.BackoffMaximum < config.BackoffInitial {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' must not be shorter than '%s', got '%s'",
+			EventsBackoffMaximumFieldName, EventsBackoffInitialFieldName, config.BackoffMaximum,
+		)))
+	}
+
+	return err
+}
+
+// Settings return settings of events by names of fields, the secret is not redacted
+func (config *Events) Settings() map[string]interface{} {
+	return map[string]interface{}{
+		EventsWebhooksFieldName:       config.Webhooks,
+		EventsSecretFieldName:         config.Secret,
+		EventsPublishersFieldName:     config.Publishers,
+		EventsNatsUrlFieldName:        config.NatsUrl,
+		EventsNatsSubjectFieldName:    config.NatsSubject,
+		EventsKafkaBrokersFieldName:   config.KafkaBrokers,
+		EventsKafkaTopicFieldName:     config.KafkaTopic,
+		EventsDelayFieldName:          config.Delay.String(),
+		EventsBatchFieldName:          config.Batch,
+		EventsAttemptsFieldName:       config.Attempts,
+		EventsBackoffInitialFieldName: config.BackoffInitial.String(),
+		EventsBackoffMaximumFieldName: config.BackoffMaximum.String(),
+		EventsTimeoutFieldName:        config.Timeout.String(),
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"github.com/diez37/go-packages/configurator"
+)
+
+const (
+	MigratorSkipFieldName = "migrator.skip"
+
+	MigratorSkipDefault = false
+)
+
+type Migrator struct {
+	// Skip not applying migrations on start of server, they are applied by 'migrate up'
+	Skip bool
+}
+
+func NewMigrator() *Migrator {
+	return &Migrator{}
+}
+
+func (config *Migrator) Configure(configurator configurator.Configurator) {
+	configurator.SetDefault(MigratorSkipFieldName, MigratorSkipDefault)
+
+	if skip := configurator.GetBool(MigratorSkipFieldName); !config.Skip {
+		config.Skip = skip
+	}
+}
+
+func (config *Migrator) Settings() map[string]interface{} {
+	return map[string]interface{}{
+		MigratorSkipFieldName: config.Skip,
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/diez37/go-packages/configurator"
+	"strings"
+)
+
+const (
+	ProfileDev        = "dev"
+	ProfileStaging    = "staging"
+	ProfileProduction = "production"
+
+	ProfileFieldName = "profile"
+
+	ProfileDefault = ProfileProduction
+)
+
+var (
+	Profiles = []string{ProfileDev, ProfileStaging, ProfileProduction}
+)
+
+// Profile environment of deployment, only profile 'dev' allows insecure configuration
+type Profile struct {
+	Name string
+}
+
+func NewProfile() *Profile {
+	return &Profile{}
+}
+
+func (config *Profile) Configure(configurator configurator.Configurator) {
+	configurator.SetDefault(ProfileFieldName, ProfileDefault)
+
+	if name := configurator.GetString(ProfileFieldName); config.Name == "" || config.Name == ProfileDefault {
+		config.Name = name
+	}
+}
+
+// IsDev checking that profile allows insecure configuration
+func (config *Profile) IsDev() bool {
+	return config.Name == ProfileDev
+}
+
+func (config *Profile) Validate() error {
+	if !contains(Profiles, config.Name) {
+		return errors.New(fmt.Sprintf("config: '%s' has unknown profile '%s', availably [%s]", ProfileFieldName, config.Name, strings.Join(Profiles, ",")))
+	}
+
+	return nil
+}
+
+func (config *Profile) Settings() map[string]interface{} {
+	return map[string]interface{}{
+		ProfileFieldName: config.Name,
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/diez37/go-packages/configurator"
+	"go.uber.org/multierr"
+	"net/url"
+	"time"
+)
+
+const (
+	SecretsDelayFieldName        = "secrets.delay"
+	SecretsTimeoutFieldName      = "secrets.timeout"
+	SecretsVaultAddressFieldName = "secrets.vault.address"
+	SecretsVaultTokenFieldName   = "secrets.vault.token"
+
+	SecretsDelayDefault   = 30 * time.Second
+	SecretsTimeoutDefault = 5 * time.Second
+)
+
+type Secrets struct {
+	// Delay between re-readings of referenced secrets and keyring, zero value disabled re-reading
+	Delay time.Duration
+	// Timeout of reading of secret from remote provider
+	Timeout time.Duration
+	// VaultAddress address of Vault, empty value disabled references 'vault://'
+	VaultAddress string
+	// VaultToken token of Vault, it may be reference 'file://' or 'env://'
+	VaultToken string
+}
+
+func NewSecrets() *Secrets {
+	return &Secrets{}
+}
+
+func (config *Secrets) Configure(configurator configurator.Configurator) {
+	configurator.SetDefault(SecretsDelayFieldName, SecretsDelayDefault)
+	configurator.SetDefault(SecretsTimeoutFieldName, SecretsTimeoutDefault)
+
+	if delay := configurator.GetDuration(SecretsDelayFieldName); config.Delay == SecretsDelayDefault {
+		config.Delay = delay
+	}
+
+	if timeout := configurator.GetDuration(SecretsTimeoutFieldName); config.Timeout == 0 || config.Timeout == SecretsTimeoutDefault {
+		config.Timeout = timeout
+	}
+
+	if address := configurator.GetString(SecretsVaultAddressFieldName); config.VaultAddress == "" {
+		config.VaultAddress = address
+	}
+
+	if token := configurator.GetString(SecretsVaultTokenFieldName); config.VaultToken == "" {
+		config.VaultToken = token
+	}
+}
+
+func (config *Secrets) Validate() error {
+	var err error
+
+	if config.Delay < 0 {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must not be negative, got '%s'", SecretsDelayFieldName, config.Delay)))
+	}
+
+	if config.Timeout <= 0 {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must be positive, got '%s'", SecretsTimeoutFieldName, config.Timeout)))
+	}
+
+	if config.VaultAddress == "" {
+		return err
+	}
+
+	if parsed, parseErr := url.Parse(config.VaultAddress); parseErr != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' has invalid url '%s', http or https url is expected", SecretsVaultAddressFieldName, config.VaultAddress)))
+	}
+
+	if config.VaultToken == "" {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' is required with '%s'", SecretsVaultTokenFieldName, SecretsVaultAddressFieldName)))
+	}
+
+	return err
+}
+
+// Settings return settings of secrets by names of fields, the token of Vault is not redacted
+func (config *Secrets) Settings() map[string]interface{} {
+	return map[string]interface{}{
+		SecretsDelayFieldName:        config.Delay.String(),
+		SecretsTimeoutFieldName:      config.Timeout.String(),
+		SecretsVaultAddressFieldName: config.VaultAddress,
+		SecretsVaultTokenFieldName:   config.VaultToken,
+	}
+}
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"github.com/diez37/go-packages/configurator"
+	"go.uber.org/multierr"
+	"strings"
 	"time"
 )
 
@@ -14,43 +18,124 @@ const (
 	TokensAccessViolationActionDisableCurrent = "disable_current"
 	TokensAccessViolationActionNone           = "none"
 
+	TokensBufferOverflowBlock = "block"
+	TokensBufferOverflowFail  = "fail"
+
 	TokensSecretFieldName                = "tokens.secret"
+	TokensKeyringFieldName               = "tokens.keyring"
 	TokensMaximumTokensFieldName         = "tokens.maximum"
 	TokensDelayClearFieldName            = "tokens.delay.clear"
 	TokensDelayBlockerFieldName          = "tokens.delay.blocker"
 	TokensDelaySaverFieldName            = "tokens.delay.saver"
+	TokensDelayRetentionFieldName        = "tokens.delay.retention"
+	TokensArchiveRetentionFieldName      = "tokens.archive.retention"
+	TokensSaverJournalFieldName          = "tokens.saver.journal"
+	TokensBlockerJournalFieldName        = "tokens.blocker.journal"
+	TokensSynchronousFieldName           = "tokens.synchronous"
+	TokensClearLeaseFieldName            = "tokens.clear.lease"
+	TokensPeersBrokerFieldName           = "tokens.peers.broker"
+	TokensPeersTTLFieldName              = "tokens.peers.ttl"
+	TokensSaverLimitFieldName            = "tokens.saver.limit"
+	TokensBlockerLimitFieldName          = "tokens.blocker.limit"
+	TokensBufferFlushFieldName           = "tokens.buffer.flush"
+	TokensBufferChunkFieldName           = "tokens.buffer.chunk"
+	TokensBufferOverflowFieldName        = "tokens.buffer.overflow"
+	TokensReadySaverFieldName            = "tokens.ready.saver"
+	TokensReadyBlockerFieldName          = "tokens.ready.blocker"
+	TokensReadyDrainFieldName            = "tokens.ready.drain"
 	TokensAccessLifetimeFieldName        = "tokens.access.lifetime"
 	TokensRefreshLifetimeFieldName       = "tokens.refresh.lifetime"
 	TokensCheckFieldsForRefreshFieldName = "tokens.refresh.check"
 	TokensRefreshActionOnAccessViolation = "tokens.refresh.action.access_violation"
+	TokensReloadDelayFieldName           = "tokens.reload.delay"
 
 	TokensSecretDefault                = "fpbxsfhdYzd3U908O5hQ"
 	TokensMaximumTokensDefault         = uint(5)
 	TokensDelayClearDefault            = 10 * time.Second
 	TokensDelayBlockerDefault          = 10 * time.Second
 	TokensDelaySaverDefault            = 5 * time.Second
+	TokensDelayRetentionDefault        = time.Hour
+	TokensArchiveRetentionDefault      = time.Hour * 24 * 90
 	TokensAccessLifetimeDefault        = 30 * time.Minute
 	TokensRefreshLifetimeDefault       = time.Hour * 24 * 30 * 2
 	TokensAccessViolationActionDefault = TokensAccessViolationActionDisableCurrent
+	TokensSynchronousDefault           = false
+	TokensPeersTTLDefault              = time.Minute
+	TokensSaverLimitDefault            = uint(100000)
+	TokensBlockerLimitDefault          = uint(100000)
+	TokensBufferFlushDefault           = uint(5000)
+	TokensBufferChunkDefault           = uint(500)
+	TokensBufferOverflowDefault        = TokensBufferOverflowFail
+	TokensReloadDelayDefault           = 10 * time.Second
+	TokensReadyDrainDefault            = 5 * time.Second
 )
 
 var (
 	TokensCheckFieldsForRefresh = []string{TokenRefreshFieldFingerprint, TokenRefreshFieldUserAgent}
+
+	TokenRefreshFields           = []string{TokenRefreshFieldIp, TokenRefreshFieldFingerprint, TokenRefreshFieldUserAgent}
+	TokensAccessViolationActions = []string{TokensAccessViolationActionDisableAll, TokensAccessViolationActionDisableCurrent, TokensAccessViolationActionNone}
+	TokensBufferOverflowActions  = []string{TokensBufferOverflowBlock, TokensBufferOverflowFail}
 )
 
 type Token struct {
 	Secret string
+	// Keyring path to file of keys of signing, empty value signs tokens by Secret
+	Keyring string
 
 	MaximumTokens uint
 	DelayClear    time.Duration
 	DelayBlocker  time.Duration
 	DelaySaver    time.Duration
 
+	// DelayRetention delay between purges of archive
+	DelayRetention time.Duration
+	// ArchiveRetention lifetime of revoked and expired tokens in archive, zero value keeps archive forever
+	ArchiveRetention time.Duration
+
+	// ClearLease lifetime of lease for clear and retention between replicas, zero value disabled election and every replica runs them
+	ClearLease time.Duration
+
+	// SaverJournal path to journal of not saved tokens, empty value disabled journal
+	SaverJournal string
+
+	// BlockerJournal path to journal of not applied blocks, empty value disabled journal
+	BlockerJournal string
+
+	// Synchronous writing tokens to repository before response, without saver and blocker
+	Synchronous bool
+
+	// PeersBroker broker for sharing not yet written tokens and blocks with other replicas, empty value disabled sharing
+	PeersBroker string
+	// PeersTTL lifetime of token received from other replica, must be longer than DelaySaver
+	PeersTTL time.Duration
+
+	// SaverLimit and BlockerLimit maximum size of buffers, zero value disabled limit
+	SaverLimit   uint
+	BlockerLimit uint
+	// BufferFlush size of buffer which starts writing without waiting of delay, zero value disabled it
+	BufferFlush uint
+	// BufferChunk maximum number of tokens in one request to repository
+	BufferChunk uint
+	// BufferOverflow action on full buffer, 'block' waits for free space, 'fail' returns error
+	BufferOverflow string
+
+	// ReadySaver and ReadyBlocker maximum size of buffers of ready service, zero value uses limit of buffer
+	ReadySaver   uint
+	ReadyBlocker uint
+	// ReadyDrain pause between not ready service and shutdown of http server, so that balancers stop
+	// sending requests before the server stops accepting them, zero value shuts down immediately
+	ReadyDrain time.Duration
+
 	AccessLifetime  time.Duration
 	RefreshLifetime time.Duration
 
 	AccessViolation    string
 	RefreshCheckFields []string
+
+	// ReloadDelay delay between checks of change of config file, the changed AccessLifetime, MaximumTokens,
+	// RefreshCheckFields and AccessViolation are applied without restart, zero value reloads only on SIGHUP
+	ReloadDelay time.Duration
 }
 
 func NewToken() *Token {
@@ -60,22 +145,304 @@ func NewToken() *Token {
 func (config *Token) Configure(configurator configurator.Configurator) {
 	configurator.SetDefault(TokensMaximumTokensFieldName, TokensMaximumTokensDefault)
 	configurator.SetDefault(TokensDelayClearFieldName, TokensDelayClearDefault)
+	configurator.SetDefault(TokensDelayBlockerFieldName, TokensDelayBlockerDefault)
+	configurator.SetDefault(TokensDelaySaverFieldName, TokensDelaySaverDefault)
+	configurator.SetDefault(TokensDelayRetentionFieldName, TokensDelayRetentionDefault)
+	configurator.SetDefault(TokensArchiveRetentionFieldName, TokensArchiveRetentionDefault)
+	configurator.SetDefault(TokensSynchronousFieldName, TokensSynchronousDefault)
+	configurator.SetDefault(TokensPeersTTLFieldName, TokensPeersTTLDefault)
+	configurator.SetDefault(TokensSaverLimitFieldName, TokensSaverLimitDefault)
+	configurator.SetDefault(TokensBlockerLimitFieldName, TokensBlockerLimitDefault)
+	configurator.SetDefault(TokensBufferFlushFieldName, TokensBufferFlushDefault)
+	configurator.SetDefault(TokensBufferChunkFieldName, TokensBufferChunkDefault)
+	configurator.SetDefault(TokensBufferOverflowFieldName, TokensBufferOverflowDefault)
 	configurator.SetDefault(TokensAccessLifetimeFieldName, TokensAccessLifetimeDefault)
 	configurator.SetDefault(TokensRefreshLifetimeFieldName, TokensRefreshLifetimeDefault)
+	configurator.SetDefault(TokensCheckFieldsForRefreshFieldName, TokensCheckFieldsForRefresh)
+	configurator.SetDefault(TokensRefreshActionOnAccessViolation, TokensAccessViolationActionDefault)
+	configurator.SetDefault(TokensReloadDelayFieldName, TokensReloadDelayDefault)
+	configurator.SetDefault(TokensReadyDrainFieldName, TokensReadyDrainDefault)
 
-	if maximumTokens := configurator.GetUint(TokensMaximumTokensFieldName); config.MaximumTokens == 0 || config.MaximumTokens == TokensMaximumTokensDefault {
+	if secret := configurator.GetString(TokensSecretFieldName); secret != "" && (config.Secret == "" || config.Secret == TokensSecretDefault) {
+		config.Secret = secret
+	}
+
+	if keyring := configurator.GetString(TokensKeyringFieldName); config.Keyring == "" {
+		config.Keyring = keyring
+	}
+
+	if maximumTokens := configurator.GetUint(TokensMaximumTokensFieldName); config.MaximumTokens == TokensMaximumTokensDefault {
 		config.MaximumTokens = maximumTokens
 	}
 
-	if delayClear := configurator.GetDuration(TokensDelayClearFieldName); config.DelayClear == 0 || config.DelayClear == TokensDelayClearDefault {
+	if delayClear := configurator.GetDuration(TokensDelayClearFieldName); config.DelayClear == TokensDelayClearDefault {
 		config.DelayClear = delayClear
 	}
 
-	if lifetime := configurator.GetDuration(TokensAccessLifetimeFieldName); config.AccessLifetime == 0 || config.AccessLifetime == TokensAccessLifetimeDefault {
+	if delay := configurator.GetDuration(TokensDelayBlockerFieldName); config.DelayBlocker == TokensDelayBlockerDefault {
+		config.DelayBlocker = delay
+	}
+
+	if delay := configurator.GetDuration(TokensDelaySaverFieldName); config.DelaySaver == TokensDelaySaverDefault {
+		config.DelaySaver = delay
+	}
+
+	if delay := configurator.GetDuration(TokensDelayRetentionFieldName); config.DelayRetention == TokensDelayRetentionDefault {
+		config.DelayRetention = delay
+	}
+
+	if retention := configurator.GetDuration(TokensArchiveRetentionFieldName); config.ArchiveRetention == TokensArchiveRetentionDefault {
+		config.ArchiveRetention = retention
+	}
+
+	if lease := configurator.GetDuration(TokensClearLeaseFieldName); config.ClearLease == 0 {
+		config.ClearLease = lease
+	}
+
+	if journal := configurator.GetString(TokensSaverJournalFieldName); config.SaverJournal == "" {
+		config.SaverJournal = journal
+	}
+
+	if journal := configurator.GetString(TokensBlockerJournalFieldName); config.BlockerJournal == "" {
+		config.BlockerJournal = journal
+	}
+
+	if synchronous := configurator.GetBool(TokensSynchronousFieldName); !config.Synchronous {
+		config.Synchronous = synchronous
+	}
+
+	if broker := configurator.GetString(TokensPeersBrokerFieldName); config.PeersBroker == "" {
+		config.PeersBroker = broker
+	}
+
+	if ttl := configurator.GetDuration(TokensPeersTTLFieldName); config.PeersTTL == TokensPeersTTLDefault {
+		config.PeersTTL = ttl
+	}
+
+	if limit := configurator.GetUint(TokensSaverLimitFieldName); config.SaverLimit == TokensSaverLimitDefault {
+		config.SaverLimit = limit
+	}
+
+	if limit := configurator.GetUint(TokensBlockerLimitFieldName); config.BlockerLimit == TokensBlockerLimitDefault {
+		config.BlockerLimit = limit
+	}
+
+	if flush := configurator.GetUint(TokensBufferFlushFieldName); config.BufferFlush == TokensBufferFlushDefault {
+		config.BufferFlush = flush
+	}
+
+	if chunk := configurator.GetUint(TokensBufferChunkFieldName); config.BufferChunk == TokensBufferChunkDefault {
+		config.BufferChunk = chunk
+	}
+
+	if overflow := configurator.GetString(TokensBufferOverflowFieldName); config.BufferOverflow == "" || config.BufferOverflow == TokensBufferOverflowDefault {
+		config.BufferOverflow = overflow
+	}
+
+	if ready := configurator.GetUint(TokensReadySaverFieldName); config.ReadySaver == 0 {
+		config.ReadySaver = ready
+	}
+
+	if ready := configurator.GetUint(TokensReadyBlockerFieldName); config.ReadyBlocker == 0 {
+		config.ReadyBlocker = ready
+	}
+
+	if drain := configurator.GetDuration(TokensReadyDrainFieldName); config.ReadyDrain == TokensReadyDrainDefault {
+		config.ReadyDrain = drain
+	}
+
+	if lifetime := configurator.GetDuration(TokensAccessLifetimeFieldName); config.AccessLifetime == TokensAccessLifetimeDefault {
 		config.AccessLifetime = lifetime
 	}
 
-	if lifetime := configurator.GetDuration(TokensRefreshLifetimeFieldName); config.RefreshLifetime == 0 || config.RefreshLifetime == TokensRefreshLifetimeDefault {
+	if lifetime := configurator.GetDuration(TokensRefreshLifetimeFieldName); config.RefreshLifetime == TokensRefreshLifetimeDefault {
 		config.RefreshLifetime = lifetime
 	}
+
+	if fields := configurator.GetStringSlice(TokensCheckFieldsForRefreshFieldName); len(config.RefreshCheckFields) == 0 || equalStrings(config.RefreshCheckFields, TokensCheckFieldsForRefresh) {
+		config.RefreshCheckFields = fields
+	}
+
+	if action := configurator.GetString(TokensRefreshActionOnAccessViolation); config.AccessViolation == "" || config.AccessViolation == TokensAccessViolationActionDefault {
+		config.AccessViolation = action
+	}
+
+	if delay := configurator.GetDuration(TokensReloadDelayFieldName); config.ReloadDelay == TokensReloadDelayDefault {
+		config.ReloadDelay = delay
+	}
+}
+
+// Validate checking every setting of tokens, the error lists every invalid setting,
+// the lease of election is shared with delivery of events
+func (config *Token) Validate(events *Events) error {
+	var err error
+
+	if config.Secret == "" && config.Keyring == "" {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' or '%s' is required", TokensSecretFieldName, TokensKeyringFieldName)))
+	}
+
+	if config.MaximumTokens == 0 {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must be at least 1", TokensMaximumTokensFieldName)))
+	}
+
+	delays := []struct {
+		name  string
+		delay time.Duration
+	}{
+		{name: TokensDelayClearFieldName, delay: config.DelayClear},
+		{name: TokensDelayBlockerFieldName, delay: config.DelayBlocker},
+		{name: TokensDelaySaverFieldName, delay: config.DelaySaver},
+		{name: TokensDelayRetentionFieldName, delay: config.DelayRetention},
+		{name: TokensAccessLifetimeFieldName, delay: config.AccessLifetime},
+		{name: TokensRefreshLifetimeFieldName, delay: config.RefreshLifetime},
+	}
+
+	for _, delay := range delays {
+		if delay.delay <= 0 {
+			err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must be positive, got '%s'", delay.name, delay.delay)))
+		}
+	}
+
+	if config.ArchiveRetention < 0 {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must not be negative, got '%s'", TokensArchiveRetentionFieldName, config.ArchiveRetention)))
+	}
+
+	if config.ClearLease < 0 {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must not be negative, got '%s'", TokensClearLeaseFieldName, config.ClearLease)))
+	} else if config.ClearLease > 0 && (config.ClearLease <= config.DelayClear || config.ClearLease <= config.DelayRetention || config.ClearLease <= events.Delay) {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' must be longer than '%s', '%s' and '%s', got '%s'",
+			TokensClearLeaseFieldName, TokensDelayClearFieldName, TokensDelayRetentionFieldName, EventsDelayFieldName, config.ClearLease,
+		)))
+	}
+
+	if config.ReloadDelay < 0 {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must not be negative, got '%s'", TokensReloadDelayFieldName, config.ReloadDelay)))
+	}
+
+	if config.ReadyDrain < 0 {
+		err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' must not be negative, got '%s'", TokensReadyDrainFieldName, config.ReadyDrain)))
+	}
+
+	if config.RefreshLifetime > 0 && config.RefreshLifetime <= config.AccessLifetime {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' must be longer than '%s', got '%s' and '%s'",
+			TokensRefreshLifetimeFieldName, TokensAccessLifetimeFieldName, config.RefreshLifetime, config.AccessLifetime,
+		)))
+	}
+
+	if config.PeersBroker != "" && config.PeersTTL <= config.DelaySaver {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' must be longer than '%s', got '%s' and '%s'",
+			TokensPeersTTLFieldName, TokensDelaySaverFieldName, config.PeersTTL, config.DelaySaver,
+		)))
+	}
+
+	if config.SaverLimit > 0 && config.BufferFlush > config.SaverLimit {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' must not be greater than '%s', got %d and %d",
+			TokensBufferFlushFieldName, TokensSaverLimitFieldName, config.BufferFlush, config.SaverLimit,
+		)))
+	}
+
+	if config.SaverLimit > 0 && config.ReadySaver > config.SaverLimit {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' must not be greater than '%s', got %d and %d",
+			TokensReadySaverFieldName, TokensSaverLimitFieldName, config.ReadySaver, config.SaverLimit,
+		)))
+	}
+
+	if config.BlockerLimit > 0 && config.ReadyBlocker > config.BlockerLimit {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' must not be greater than '%s', got %d and %d",
+			TokensReadyBlockerFieldName, TokensBlockerLimitFieldName, config.ReadyBlocker, config.BlockerLimit,
+		)))
+	}
+
+	if !contains(TokensBufferOverflowActions, config.BufferOverflow) {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' has unknown action '%s', availably [%s]",
+			TokensBufferOverflowFieldName, config.BufferOverflow, strings.Join(TokensBufferOverflowActions, ","),
+		)))
+	}
+
+	if !contains(TokensAccessViolationActions, config.AccessViolation) {
+		err = multierr.Append(err, errors.New(fmt.Sprintf(
+			"config: '%s' has unknown action '%s', availably [%s]",
+			TokensRefreshActionOnAccessViolation, config.AccessViolation, strings.Join(TokensAccessViolationActions, ","),
+		)))
+	}
+
+	checked := map[string]bool{}
+	for _, field := range config.RefreshCheckFields {
+		if !contains(TokenRefreshFields, field) {
+			err = multierr.Append(err, errors.New(fmt.Sprintf(
+				"config: '%s' has unknown field '%s', availably [%s]",
+				TokensCheckFieldsForRefreshFieldName, field, strings.Join(TokenRefreshFields, ","),
+			)))
+		} else if checked[field] {
+			err = multierr.Append(err, errors.New(fmt.Sprintf("config: '%s' has field '%s' twice", TokensCheckFieldsForRefreshFieldName, field)))
+		}
+
+		checked[field] = true
+	}
+
+	return err
+}
+
+// Settings return settings of tokens by names of fields, the secret is not redacted
+func (config *Token) Settings() map[string]interface{} {
+	return map[string]interface{}{
+		TokensSecretFieldName:                config.Secret,
+		TokensKeyringFieldName:               config.Keyring,
+		TokensMaximumTokensFieldName:         config.MaximumTokens,
+		TokensDelayClearFieldName:            config.DelayClear.String(),
+		TokensDelayBlockerFieldName:          config.DelayBlocker.String(),
+		TokensDelaySaverFieldName:            config.DelaySaver.String(),
+		TokensDelayRetentionFieldName:        config.DelayRetention.String(),
+		TokensArchiveRetentionFieldName:      config.ArchiveRetention.String(),
+		TokensClearLeaseFieldName:            config.ClearLease.String(),
+		TokensSaverJournalFieldName:          config.SaverJournal,
+		TokensBlockerJournalFieldName:        config.BlockerJournal,
+		TokensSynchronousFieldName:           config.Synchronous,
+		TokensPeersBrokerFieldName:           config.PeersBroker,
+		TokensPeersTTLFieldName:              config.PeersTTL.String(),
+		TokensSaverLimitFieldName:            config.SaverLimit,
+		TokensBlockerLimitFieldName:          config.BlockerLimit,
+		TokensBufferFlushFieldName:           config.BufferFlush,
+		TokensBufferChunkFieldName:           config.BufferChunk,
+		TokensBufferOverflowFieldName:        config.BufferOverflow,
+		TokensReadySaverFieldName:            config.ReadySaver,
+		TokensReadyBlockerFieldName:          config.ReadyBlocker,
+		TokensReadyDrainFieldName:            config.ReadyDrain.String(),
+		TokensAccessLifetimeFieldName:        config.AccessLifetime.String(),
+		TokensRefreshLifetimeFieldName:       config.RefreshLifetime.String(),
+		TokensCheckFieldsForRefreshFieldName: config.RefreshCheckFields,
+		TokensRefreshActionOnAccessViolation: config.AccessViolation,
+		TokensReloadDelayFieldName:           config.ReloadDelay.String(),
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func equalStrings(left, right []string) bool {
+	if len(left) != len(right) {
+		return false
+	}
+
+	for index := range left {
+		if left[index] != right[index] {
+			return false
+		}
+	}
+
+	return true
 }
@@ -0,0 +1,32 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Diez37/go-skeleton/infrastructure/broker"
+	"github.com/diez37/go-packages/container"
+	"github.com/go-redis/redis/v8"
+)
+
+// Broker creating broker.Broker by name, the redis client is resolved from container
+func Broker(container container.Container, name string) (broker.Broker, error) {
+	switch name {
+	case broker.RedisBroker:
+		var tokenBroker broker.Broker
+
+		err := container.Invoke(func(client redis.Cmdable) error {
+			redisClient, ok := client.(*redis.Client)
+			if !ok {
+				return errors.New("broker: redis client unknown")
+			}
+
+			tokenBroker = broker.NewRedis(redisClient)
+
+			return nil
+		})
+
+		return tokenBroker, err
+	}
+
+	return nil, errors.New(fmt.Sprintf("broker: '%s' unknown", name))
+}
@@ -2,15 +2,41 @@ package container
 
 import (
 	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/keyring"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
 	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/diez37/go-packages/configurator"
 	"github.com/diez37/go-packages/container"
 	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func AddProvide(container container.Container) error {
 	return container.Provides(
-		repository.NewSql,
+		func(config *db.Config, configurator configurator.Configurator, tracer trace.Tracer) (repository.Repository, error) {
+			return Repository(container, config, configurator, tracer)
+		},
+		func(config *db.Config, configurator configurator.Configurator, tracer trace.Tracer) (repository.Lease, error) {
+			return Lease(container, config, configurator, tracer)
+		},
+		func(config *db.Config, configurator configurator.Configurator, tracer trace.Tracer) (repository.Outbox, error) {
+			return Outbox(container, config, configurator, tracer)
+		},
+		Bolt,
+		Keyring,
+		func(watched keyring.Watched) keyring.Keyring {
+			return watched
+		},
+		Resolver,
 		config.NewToken,
+		config.NewCookie,
+		config.NewBolt,
+		config.NewEvents,
+		config.NewMigrator,
+		config.NewProfile,
+		config.NewSecrets,
+		metrics.NewMetrics,
 		validator.New,
 	)
 }
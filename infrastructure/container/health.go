@@ -0,0 +1,93 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/diez37/go-packages/configurator"
+	"github.com/diez37/go-packages/container"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/golang-migrate/migrate/v4"
+	"go.etcd.io/bbolt"
+)
+
+// pinger database which checks its connection
+type pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Ping creating check of connection to storage, the storage in memory has no check
+func Ping(
+	container container.Container,
+	dbConfig *db.Config,
+	configurator configurator.Configurator,
+) (func(ctx context.Context) error, error) {
+	var check func(ctx context.Context) error
+
+	switch Driver(dbConfig, configurator) {
+	case repository.MemoryDriver:
+		return nil, nil
+	case repository.BoltDriver:
+		err := container.Invoke(func(db *bbolt.DB) {
+			check = func(_ context.Context) error {
+				return db.View(func(_ *bbolt.Tx) error { return nil })
+			}
+		})
+
+		return check, err
+	}
+
+	err := container.Invoke(func(db goqu.SQLDatabase) error {
+		pinger, ok := db.(pinger)
+		if !ok {
+			return errors.New(fmt.Sprintf("container: database '%T' not supported ping", db))
+		}
+
+		check = pinger.PingContext
+
+		return nil
+	})
+
+	return check, err
+}
+
+// Migrated creating check that the latest migration of source is applied and not dirty, storages without migrations have no check
+func Migrated(
+	container container.Container,
+	dbConfig *db.Config,
+	configurator configurator.Configurator,
+) (func(ctx context.Context) error, error) {
+	switch Driver(dbConfig, configurator) {
+	case repository.MemoryDriver, repository.BoltDriver:
+		return nil, nil
+	}
+
+	migrator, err := Migrator(container)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := Migrations(container)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(_ context.Context) error {
+		version, dirty, err := migrator.Version()
+		if err != nil && (err != migrate.ErrNilVersion || len(versions) > 0) {
+			return err
+		}
+
+		if dirty {
+			return errors.New(fmt.Sprintf("migrations: version %d is dirty", version))
+		}
+
+		if len(versions) > 0 && version < versions[len(versions)-1] {
+			return errors.New(fmt.Sprintf("migrations: version %d, latest - %d", version, versions[len(versions)-1]))
+		}
+
+		return nil
+	}, nil
+}
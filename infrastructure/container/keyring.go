@@ -0,0 +1,136 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/keyring"
+	"github.com/Diez37/go-skeleton/infrastructure/secret"
+	"github.com/diez37/go-packages/configurator"
+	"github.com/diez37/go-packages/log"
+	"strings"
+	"time"
+)
+
+const keyringFilePrefix = secret.SchemeFile + "://"
+
+// Keyring loading keyring of signing from file or reference of configuration, without keyring the tokens are signed by secret.
+// The secret and keyring are re-read by Process of keyring.Watched. The profiles except 'dev' refuse missing, default and weak keys
+func Keyring(
+	tokenConfig *config.Token,
+	profileConfig *config.Profile,
+	secretsConfig *config.Secrets,
+	resolver secret.Resolver,
+	configurator configurator.Configurator,
+	logger log.Logger,
+) (keyring.Watched, error) {
+	profileConfig.Configure(configurator)
+
+	if err := profileConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), secretsConfig.Timeout)
+	defer cancelFunc()
+
+	read := func(ctx context.Context) ([]byte, error) {
+		return resolver.Resolve(ctx, tokenConfig.Secret)
+	}
+
+	if tokenConfig.Keyring == "" {
+		return keyring.NewWatched(ctx, config.TokensSecretFieldName, read, func(content []byte) (keyring.Keyring, error) {
+			if err := checkSecret(string(content), profileConfig, logger); err != nil {
+				return nil, err
+			}
+
+			return keyring.NewSecret(string(content)), nil
+		}, logger)
+	}
+
+	reference := tokenConfig.Keyring
+	if !resolver.IsReference(reference) {
+		reference = keyringFilePrefix + reference
+	}
+
+	read = func(ctx context.Context) ([]byte, error) {
+		return resolver.Resolve(ctx, reference)
+	}
+
+	return keyring.NewWatched(ctx, config.TokensKeyringFieldName, read, func(content []byte) (keyring.Keyring, error) {
+		return buildKeyring(content, profileConfig, logger)
+	}, logger)
+}
+
+// KeyringFile return path to file of keyring from value of flag 'tokens.keyring', only keyring in file is writable
+func KeyringFile(value string) (string, error) {
+	if strings.HasPrefix(value, keyringFilePrefix) {
+		return strings.TrimPrefix(value, keyringFilePrefix), nil
+	}
+
+	if strings.Contains(value, "://") {
+		return "", errors.New(fmt.Sprintf("keyring: '%s' is not file, only keyring in file is writable", value))
+	}
+
+	return value, nil
+}
+
+// buildKeyring creating keyring of content of file of keyring, it requires active key
+func buildKeyring(content []byte, profileConfig *config.Profile, logger log.Logger) (keyring.Keyring, error) {
+	keys, err := keyring.Unmarshal(content)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenKeyring, err := keyring.NewKeyring(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tokenKeyring.Signing(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(time.UTC)
+
+	for _, key := range keys {
+		if key.State(now) == keyring.StateRetired {
+			continue
+		}
+
+		if err := key.Check(); err != nil {
+			if !profileConfig.IsDev() {
+				return nil, errors.New(fmt.Sprintf("profile '%s': %s", profileConfig.Name, err))
+			}
+
+			logger.Warnf("profile '%s': %s", profileConfig.Name, err)
+		}
+	}
+
+	return tokenKeyring, nil
+}
+
+// checkSecret checking secret of tokens, in profile 'dev' the weak secret is only warned
+func checkSecret(secret string, profileConfig *config.Profile, logger log.Logger) error {
+	var err error
+
+	switch secret {
+	case "":
+		err = errors.New(fmt.Sprintf("secret of tokens is missing, set '%s' or '%s'", config.TokensSecretFieldName, config.TokensKeyringFieldName))
+	case config.TokensSecretDefault:
+		err = errors.New(fmt.Sprintf("secret of tokens is the default value, set own '%s' or '%s'", config.TokensSecretFieldName, config.TokensKeyringFieldName))
+	default:
+		err = keyring.NewSecretKey(secret).Check()
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	if profileConfig.IsDev() {
+		logger.Warnf("profile '%s': %s", profileConfig.Name, err)
+		return nil
+	}
+
+	return errors.New(fmt.Sprintf("profile '%s': %s", profileConfig.Name, err))
+}
@@ -0,0 +1,92 @@
+package container
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/publisher"
+	"github.com/Diez37/go-skeleton/infrastructure/secret"
+	"github.com/Diez37/go-skeleton/infrastructure/webhook"
+	"github.com/diez37/go-packages/log"
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
+	"net/http"
+	"os"
+)
+
+// Publishers creating publisher.Publisher for every webhook by its url and for every publisher of configuration by name,
+// the secret of webhooks is resolved once, the webhooks without secret are refused by profiles except dev
+func Publishers(
+	ctx context.Context,
+	eventsConfig *config.Events,
+	profileConfig *config.Profile,
+	resolver secret.Resolver,
+	logger log.Logger,
+	tracer trace.Tracer,
+) (map[string]publisher.Publisher, error) {
+	publishers := make(map[string]publisher.Publisher, len(eventsConfig.Webhooks)+len(eventsConfig.Publishers))
+
+	if len(eventsConfig.Webhooks) > 0 {
+		webhookSecret, err := resolver.Resolve(ctx, eventsConfig.Secret)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(webhookSecret) == 0 {
+			err := errors.New(fmt.Sprintf("secret of webhooks is missing, set '%s'", config.EventsSecretFieldName))
+			if !profileConfig.IsDev() {
+				return nil, errors.New(fmt.Sprintf("profile '%s': %s", profileConfig.Name, err))
+			}
+
+			logger.Warnf("profile '%s': %s, webhook requests are not signed", profileConfig.Name, err)
+		}
+
+		client := &http.Client{Timeout: eventsConfig.Timeout}
+		for _, url := range eventsConfig.Webhooks {
+			publishers[url] = webhook.NewWebhook(client, url, string(webhookSecret), tracer)
+		}
+	}
+
+	for _, name := range eventsConfig.Publishers {
+		eventPublisher, err := Publisher(eventsConfig, name)
+		if err != nil {
+			for _, eventPublisher := range publishers {
+				err = multierr.Append(err, eventPublisher.Close())
+			}
+
+			return nil, err
+		}
+
+		publishers[name] = eventPublisher
+	}
+
+	return publishers, nil
+}
+
+// Publisher creating publisher.Publisher by name
+func Publisher(eventsConfig *config.Events, name string) (publisher.Publisher, error) {
+	switch name {
+	case publisher.StdoutPublisher:
+		return publisher.NewStdout(os.Stdout), nil
+	case publisher.NatsPublisher:
+		conn, err := nats.Connect(eventsConfig.NatsUrl, nats.Timeout(eventsConfig.Timeout))
+		if err != nil {
+			return nil, err
+		}
+
+		return publisher.NewNats(conn, eventsConfig.NatsSubject), nil
+	case publisher.KafkaPublisher:
+		return publisher.NewKafka(&kafka.Writer{
+			Addr:         kafka.TCP(eventsConfig.KafkaBrokers...),
+			Topic:        eventsConfig.KafkaTopic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			WriteTimeout: eventsConfig.Timeout,
+		}), nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("publisher: '%s' unknown", name))
+}
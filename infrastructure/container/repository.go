@@ -0,0 +1,202 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/diez37/go-packages/configurator"
+	"github.com/diez37/go-packages/container"
+	"github.com/diez37/go-packages/migrator"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"go.etcd.io/bbolt"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+)
+
+// Driver return name of storage driver from flags or configuration
+func Driver(dbConfig *db.Config, configurator configurator.Configurator) string {
+	if driver := configurator.GetString(db.DriverFieldName); driver != "" && dbConfig.Driver == "" {
+		dbConfig.Driver = driver
+	}
+
+	return dbConfig.Driver
+}
+
+// Repository creating repository.Repository for storage driver,
+// the sql database is resolved from container only for sql drivers
+func Repository(
+	container container.Container,
+	dbConfig *db.Config,
+	configurator configurator.Configurator,
+	tracer trace.Tracer,
+) (repository.Repository, error) {
+	switch Driver(dbConfig, configurator) {
+	case repository.MemoryDriver:
+		return repository.NewMemory(tracer), nil
+	case repository.BoltDriver:
+		var tokenRepository repository.Repository
+
+		err := container.Invoke(func(db *bbolt.DB) (err error) {
+			tokenRepository, err = repository.NewBolt(db, tracer)
+
+			return err
+		})
+
+		return tokenRepository, err
+	}
+
+	var tokenRepository repository.Repository
+
+	err := container.Invoke(func(db goqu.SQLDatabase) {
+		tokenRepository = repository.NewSql(db, tracer)
+	})
+
+	return tokenRepository, err
+}
+
+// Bolt opening embedded storage, the storage is shared by repository and outbox
+func Bolt(boltConfig *config.Bolt, configurator configurator.Configurator) (*bbolt.DB, error) {
+	boltConfig.Configure(configurator)
+
+	return bbolt.Open(boltConfig.Path, 0600, &bbolt.Options{Timeout: boltConfig.Timeout})
+}
+
+// CloseStorage closing embedded storage after the last writes, so its file lock is released before exit
+func CloseStorage(container container.Container, dbConfig *db.Config, configurator configurator.Configurator) error {
+	if Driver(dbConfig, configurator) != repository.BoltDriver {
+		return nil
+	}
+
+	return container.Invoke(func(db *bbolt.DB) error {
+		return db.Close()
+	})
+}
+
+// Lease creating repository.Lease for storage driver, storages of one process not need shared lease
+func Lease(
+	container container.Container,
+	dbConfig *db.Config,
+	configurator configurator.Configurator,
+	tracer trace.Tracer,
+) (repository.Lease, error) {
+	switch Driver(dbConfig, configurator) {
+	case repository.MemoryDriver, repository.BoltDriver:
+		return repository.NewMemoryLease(), nil
+	}
+
+	var lease repository.Lease
+
+	err := container.Invoke(func(db goqu.SQLDatabase) {
+		lease = repository.NewSqlLease(db, tracer)
+	})
+
+	return lease, err
+}
+
+// Outbox creating repository.Outbox for storage driver, events are kept in the same storage as tokens
+func Outbox(
+	container container.Container,
+	dbConfig *db.Config,
+	configurator configurator.Configurator,
+	tracer trace.Tracer,
+) (repository.Outbox, error) {
+	var outbox repository.Outbox
+
+	switch Driver(dbConfig, configurator) {
+	case repository.MemoryDriver:
+		// events attached to writes of tokens are kept by repository
+		err := container.Invoke(func(tokenRepository repository.Repository) error {
+			var ok bool
+			if outbox, ok = tokenRepository.(repository.Outbox); !ok {
+				return errors.New("container: memory repository is not outbox")
+			}
+
+			return nil
+		})
+
+		return outbox, err
+	case repository.BoltDriver:
+		err := container.Invoke(func(db *bbolt.DB) (err error) {
+			outbox, err = repository.NewBoltOutbox(db, tracer)
+
+			return err
+		})
+
+		return outbox, err
+	}
+
+	err := container.Invoke(func(db goqu.SQLDatabase) {
+		outbox = repository.NewSqlOutbox(db, tracer)
+	})
+
+	return outbox, err
+}
+
+// Migrate applying migrations for sql drivers, other drivers not needed migrations
+func Migrate(container container.Container) error {
+	return container.Invoke(func(dbConfig *db.Config, configurator configurator.Configurator) error {
+		switch Driver(dbConfig, configurator) {
+		case repository.MemoryDriver, repository.BoltDriver:
+			return nil
+		}
+
+		return container.Invoke(func(migrator *migrate.Migrate) error {
+			if err := migrator.Up(); err != nil && err != migrate.ErrNoChange {
+				return err
+			}
+
+			return nil
+		})
+	})
+}
+
+// Migrator return migrator of database, only sql drivers have migrations
+func Migrator(container container.Container) (*migrate.Migrate, error) {
+	var tokenMigrator *migrate.Migrate
+
+	err := container.Invoke(func(dbConfig *db.Config, configurator configurator.Configurator) error {
+		switch driver := Driver(dbConfig, configurator); driver {
+		case repository.MemoryDriver, repository.BoltDriver:
+			return errors.New(fmt.Sprintf("migrator: driver '%s' has no migrations", driver))
+		}
+
+		return container.Invoke(func(migrator *migrate.Migrate) {
+			tokenMigrator = migrator
+		})
+	})
+
+	return tokenMigrator, err
+}
+
+// Migrations return versions of migrations of source in ascending order
+func Migrations(container container.Container) ([]uint, error) {
+	var versions []uint
+
+	err := container.Invoke(func(migratorConfig *migrator.Config) error {
+		driver, err := source.Open(migratorConfig.Source)
+		if err != nil {
+			return err
+		}
+
+		defer driver.Close()
+
+		version, err := driver.First()
+		for err == nil {
+			versions = append(versions, version)
+
+			version, err = driver.Next(version)
+		}
+
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	})
+
+	return versions, err
+}
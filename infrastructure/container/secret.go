@@ -0,0 +1,42 @@
+package container
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/secret"
+	"github.com/diez37/go-packages/configurator"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// Resolver creating secret.Resolver of references 'file://' and 'env://',
+// the references 'vault://' are resolved when address of Vault is configured
+func Resolver(secretsConfig *config.Secrets, configurator configurator.Configurator, tracer trace.Tracer) (secret.Resolver, error) {
+	secretsConfig.Configure(configurator)
+
+	if err := secretsConfig.Validate(); err != nil {
+		return nil, err
+	}
+
+	providers := map[string]secret.Provider{
+		secret.SchemeFile: secret.NewFile(),
+		secret.SchemeEnv:  secret.NewEnv(),
+	}
+
+	if secretsConfig.VaultAddress == "" {
+		return secret.NewResolver(providers), nil
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), secretsConfig.Timeout)
+	defer cancelFunc()
+
+	token, err := secret.NewResolver(providers).Resolve(ctx, secretsConfig.VaultToken)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: secretsConfig.Timeout}
+	providers[secret.SchemeVault] = secret.NewVault(client, secretsConfig.VaultAddress, string(token), tracer)
+
+	return secret.NewResolver(providers), nil
+}
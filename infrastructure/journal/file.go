@@ -0,0 +1,179 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"go.uber.org/multierr"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	// fileHeaderLength length of record and crc32 of record
+	fileHeaderLength = 8
+)
+
+type file struct {
+	mutex *sync.Mutex
+
+	path string
+	file *os.File
+}
+
+// NewFile creating Journal in file, every record is written as 'length + crc32 + data' and synced to disk.
+// A record broken by crash in the middle of write ends the journal on replay
+func NewFile(path string) (Journal, error) {
+	descriptor, err := fileOpen(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &file{mutex: &sync.Mutex{}, path: path, file: descriptor}, nil
+}
+
+func (journal *file) Append(records ...[]byte) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	if _, err := journal.file.Write(fileEncode(records...)); err != nil {
+		return err
+	}
+
+	return journal.file.Sync()
+}
+
+func (journal *file) Replay(handler func(record []byte) error) error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	if _, err := journal.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(journal.file)
+	header := make([]byte, fileHeaderLength)
+	offset := int64(0)
+
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return journal.end(offset, err)
+		}
+
+		record := make([]byte, binary.BigEndian.Uint32(header))
+		if _, err := io.ReadFull(reader, record); err != nil {
+			return journal.end(offset, err)
+		}
+
+		if crc32.ChecksumIEEE(record) != binary.BigEndian.Uint32(header[4:]) {
+			return journal.end(offset, io.ErrUnexpectedEOF)
+		}
+
+		if err := handler(record); err != nil {
+			return err
+		}
+
+		offset += int64(fileHeaderLength + len(record))
+	}
+}
+
+func (journal *file) Truncate() error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	if err := journal.file.Truncate(0); err != nil {
+		return err
+	}
+
+	return journal.file.Sync()
+}
+
+func (journal *file) Rewrite(records ...[]byte) error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	temporaryPath := journal.path + ".tmp"
+
+	temporary, err := os.OpenFile(temporaryPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := temporary.Write(fileEncode(records...)); err != nil {
+		return multierr.Append(err, temporary.Close())
+	}
+
+	if err := temporary.Sync(); err != nil {
+		return multierr.Append(err, temporary.Close())
+	}
+
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(temporaryPath, journal.path); err != nil {
+		return err
+	}
+
+	descriptor, err := fileOpen(journal.path)
+	if err != nil {
+		return err
+	}
+
+	if err := journal.file.Close(); err != nil {
+		return multierr.Append(err, descriptor.Close())
+	}
+
+	journal.file = descriptor
+
+	return nil
+}
+
+func (journal *file) Close() error {
+	journal.mutex.Lock()
+	defer journal.mutex.Unlock()
+
+	return journal.file.Close()
+}
+
+// end the end of file is a normal end of journal, the torn tail of last write is cut off
+// so that next records are not written after it
+func (journal *file) end(offset int64, err error) error {
+	switch err {
+	case io.EOF:
+		return nil
+	case io.ErrUnexpectedEOF:
+		return journal.file.Truncate(offset)
+	}
+
+	return err
+}
+
+func fileOpen(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+}
+
+// fileEncode encoding records to 'length + crc32 + data' sequence
+func fileEncode(records ...[]byte) []byte {
+	length := 0
+	for _, record := range records {
+		length += fileHeaderLength + len(record)
+	}
+
+	buffer := make([]byte, 0, length)
+	for _, record := range records {
+		header := make([]byte, fileHeaderLength)
+		binary.BigEndian.PutUint32(header, uint32(len(record)))
+		binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(record))
+
+		buffer = append(buffer, header...)
+		buffer = append(buffer, record...)
+	}
+
+	return buffer
+}
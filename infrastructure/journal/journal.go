@@ -0,0 +1,29 @@
+package journal
+
+import "io"
+
+// Journal append-only log of records which must survive a restart of process
+type Journal interface {
+	io.Closer
+
+	// Append durably writing records, records are on disk when method returned without error
+	Append(records ...[]byte) error
+
+	// Replay calling handler for every record in order of writing
+	Replay(handler func(record []byte) error) error
+
+	// Truncate removing all records
+	Truncate() error
+
+	// Rewrite atomically replacing all records by records
+	Rewrite(records ...[]byte) error
+}
+
+// New creating file Journal on path, for empty path the journal is disabled
+func New(path string) (Journal, error) {
+	if path == "" {
+		return NewNop(), nil
+	}
+
+	return NewFile(path)
+}
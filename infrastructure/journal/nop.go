@@ -0,0 +1,28 @@
+package journal
+
+type nop struct{}
+
+// NewNop creating Journal which keeps nothing
+func NewNop() Journal {
+	return &nop{}
+}
+
+func (journal *nop) Append(_ ...[]byte) error {
+	return nil
+}
+
+func (journal *nop) Replay(_ func(record []byte) error) error {
+	return nil
+}
+
+func (journal *nop) Truncate() error {
+	return nil
+}
+
+func (journal *nop) Rewrite(_ ...[]byte) error {
+	return nil
+}
+
+func (journal *nop) Close() error {
+	return nil
+}
@@ -0,0 +1,297 @@
+package keyring
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"math/big"
+	"time"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmHS384 = "HS384"
+	AlgorithmHS512 = "HS512"
+	AlgorithmRS256 = "RS256"
+	AlgorithmRS384 = "RS384"
+	AlgorithmRS512 = "RS512"
+	AlgorithmES256 = "ES256"
+	AlgorithmES384 = "ES384"
+	AlgorithmES512 = "ES512"
+	AlgorithmEdDSA = "EdDSA"
+
+	StatePending  = "pending"
+	StateActive   = "active"
+	StateRetiring = "retiring"
+	StateRetired  = "retired"
+
+	// rsaBits size of generated RSA keys
+	rsaBits = 2048
+)
+
+var (
+	// Algorithms supported algorithms of signing
+	Algorithms = []string{
+		AlgorithmHS256, AlgorithmHS384, AlgorithmHS512,
+		AlgorithmRS256, AlgorithmRS384, AlgorithmRS512,
+		AlgorithmES256, AlgorithmES384, AlgorithmES512,
+		AlgorithmEdDSA,
+	}
+)
+
+// Key key of signing of access tokens
+type Key struct {
+	// ID identifier of key in header 'kid' of token, empty for key of secret
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	// Material private key in PEM for asymmetric algorithms or secret in base64 for HMAC
+	Material  string    `json:"material"`
+	CreatedAt time.Time `json:"created_at"`
+	// ActivateAt time from which key signs tokens, empty value for key which signs since creating
+	ActivateAt *time.Time `json:"activate_at,omitempty"`
+	// RetireAt time after which tokens of key are not accepted, empty value for active key
+	RetireAt *time.Time `json:"retire_at,omitempty"`
+
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// Generate creating new key of algorithm with random identifier
+func Generate(algorithm string) (*Key, error) {
+	var material string
+
+	switch algorithm {
+	case AlgorithmHS256, AlgorithmHS384, AlgorithmHS512:
+		secret := make([]byte, hmacSize(algorithm))
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+
+		material = base64.StdEncoding.EncodeToString(secret)
+	default:
+		privateKey, err := generatePrivate(algorithm)
+		if err != nil {
+			return nil, err
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		material = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	}
+
+	key := &Key{
+		ID:        uuid.New().String(),
+		Algorithm: algorithm,
+		Material:  material,
+		CreatedAt: time.Now().In(time.UTC),
+	}
+
+	return key, key.Parse()
+}
+
+// NewSecretKey creating HS256 key without identifier from secret
+func NewSecretKey(secret string) *Key {
+	return &Key{
+		Algorithm: AlgorithmHS256,
+		Material:  base64.StdEncoding.EncodeToString([]byte(secret)),
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+}
+
+// Parse decoding material of key, it must be called before signing and verifying
+func (key *Key) Parse() error {
+	if jwt.GetSigningMethod(key.Algorithm) == nil || !isSupported(key.Algorithm) {
+		return errors.New(fmt.Sprintf("keyring: algorithm '%s' of key '%s' not supported", key.Algorithm, key.ID))
+	}
+
+	if isHmac(key.Algorithm) {
+		secret, err := base64.StdEncoding.DecodeString(key.Material)
+		if err != nil {
+			return err
+		}
+
+		key.signKey, key.verifyKey = secret, secret
+
+		return nil
+	}
+
+	block, _ := pem.Decode([]byte(key.Material))
+	if block == nil {
+		return errors.New(fmt.Sprintf("keyring: material of key '%s' is not PEM", key.ID))
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return errors.New(fmt.Sprintf("keyring: key '%s' of type '%T' not supported", key.ID, privateKey))
+	}
+
+	key.signKey, key.verifyKey = privateKey, signer.Public()
+
+	return nil
+}
+
+// Method return method of signing of key
+func (key *Key) Method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(key.Algorithm)
+}
+
+// SignKey return key for signing by jwt
+func (key *Key) SignKey() interface{} {
+	return key.signKey
+}
+
+// VerifyKey return key for verifying by jwt
+func (key *Key) VerifyKey() interface{} {
+	return key.verifyKey
+}
+
+// State return state of key at time: pending only verifies tokens before activation, active signs tokens,
+// retiring verifies them and signs until activation of the next key, retired is not used
+func (key *Key) State(now time.Time) string {
+	switch {
+	case key.ActivateAt != nil && key.ActivateAt.After(now):
+		return StatePending
+	case key.RetireAt == nil:
+		return StateActive
+	case key.RetireAt.After(now):
+		return StateRetiring
+	}
+
+	return StateRetired
+}
+
+// ActivatedAt return time from which key signs tokens
+func (key *Key) ActivatedAt() time.Time {
+	if key.ActivateAt == nil {
+		return key.CreatedAt
+	}
+
+	return *key.ActivateAt
+}
+
+// IsSymmetric checking that key is secret of HMAC which cannot be published
+func (key *Key) IsSymmetric() bool {
+	return isHmac(key.Algorithm)
+}
+
+// PEM return private key in PEM, the secret of HMAC has no PEM
+func (key *Key) PEM() ([]byte, error) {
+	if key.IsSymmetric() {
+		return nil, errors.New(fmt.Sprintf("keyring: algorithm '%s' has no PEM, use JWK", key.Algorithm))
+	}
+
+	return []byte(key.Material), nil
+}
+
+// JWK return key in format of JSON Web Key, the private part is added only by private
+func (key *Key) JWK(private bool) (map[string]interface{}, error) {
+	jwk := map[string]interface{}{"alg": key.Algorithm, "use": "sig"}
+	if key.ID != "" {
+		jwk["kid"] = key.ID
+	}
+
+	encode := base64.RawURLEncoding.EncodeToString
+
+	switch verifyKey := key.verifyKey.(type) {
+	case []byte:
+		if !private {
+			return nil, errors.New(fmt.Sprintf("keyring: secret of key '%s' cannot be public", key.ID))
+		}
+
+		jwk["kty"], jwk["k"] = "oct", encode(verifyKey)
+	case *rsa.PublicKey:
+		jwk["kty"], jwk["n"], jwk["e"] = "RSA", encode(verifyKey.N.Bytes()), encode(big.NewInt(int64(verifyKey.E)).Bytes())
+
+		if privateKey, ok := key.signKey.(*rsa.PrivateKey); ok && private {
+			privateKey.Precompute()
+
+			jwk["d"] = encode(privateKey.D.Bytes())
+			jwk["p"], jwk["q"] = encode(privateKey.Primes[0].Bytes()), encode(privateKey.Primes[1].Bytes())
+			jwk["dp"], jwk["dq"] = encode(privateKey.Precomputed.Dp.Bytes()), encode(privateKey.Precomputed.Dq.Bytes())
+			jwk["qi"] = encode(privateKey.Precomputed.Qinv.Bytes())
+		}
+	case *ecdsa.PublicKey:
+		size := (verifyKey.Curve.Params().BitSize + 7) / 8
+
+		jwk["kty"], jwk["crv"] = "EC", verifyKey.Curve.Params().Name
+		jwk["x"], jwk["y"] = encode(verifyKey.X.FillBytes(make([]byte, size))), encode(verifyKey.Y.FillBytes(make([]byte, size)))
+
+		if privateKey, ok := key.signKey.(*ecdsa.PrivateKey); ok && private {
+			jwk["d"] = encode(privateKey.D.FillBytes(make([]byte, size)))
+		}
+	case ed25519.PublicKey:
+		jwk["kty"], jwk["crv"], jwk["x"] = "OKP", "Ed25519", encode(verifyKey)
+
+		if privateKey, ok := key.signKey.(ed25519.PrivateKey); ok && private {
+			jwk["d"] = encode(privateKey.Seed())
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("keyring: key '%s' is not parsed", key.ID))
+	}
+
+	return jwk, nil
+}
+
+func generatePrivate(algorithm string) (crypto.PrivateKey, error) {
+	switch algorithm {
+	case AlgorithmRS256, AlgorithmRS384, AlgorithmRS512:
+		return rsa.GenerateKey(rand.Reader, rsaBits)
+	case AlgorithmES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmES384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case AlgorithmES512:
+		return ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+
+		return privateKey, err
+	}
+
+	return nil, errors.New(fmt.Sprintf("keyring: algorithm '%s' not supported", algorithm))
+}
+
+// hmacSize size of secret equal to size of hash of algorithm
+func hmacSize(algorithm string) int {
+	switch algorithm {
+	case AlgorithmHS384:
+		return 48
+	case AlgorithmHS512:
+		return 64
+	}
+
+	return 32
+}
+
+func isHmac(algorithm string) bool {
+	return algorithm == AlgorithmHS256 || algorithm == AlgorithmHS384 || algorithm == AlgorithmHS512
+}
+
+func isSupported(algorithm string) bool {
+	for _, supported := range Algorithms {
+		if supported == algorithm {
+			return true
+		}
+	}
+
+	return false
+}
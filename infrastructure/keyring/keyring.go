@@ -0,0 +1,114 @@
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	NoActiveKeyError = errors.New("keyring: no active key")
+)
+
+// Keyring keys of signing of access tokens, new tokens are signed by active key
+// and tokens are verified by key of their 'kid' until the key is retired
+type Keyring interface {
+	// Signing return the last activated not retired key
+	Signing() (*Key, error)
+
+	// Verifying return not retired key by identifier
+	Verifying(id string) (*Key, error)
+
+	// Keys return all keys of keyring
+	Keys() []*Key
+}
+
+type keyring struct {
+	keys []*Key
+}
+
+// NewKeyring creating Keyring of keys, the material of every key is parsed
+func NewKeyring(keys ...*Key) (Keyring, error) {
+	for _, key := range keys {
+		if err := key.Parse(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &keyring{keys: keys}, nil
+}
+
+// NewSecret creating Keyring of one HS256 key of secret, tokens of the key have no 'kid'
+func NewSecret(secret string) Keyring {
+	return &keyring{keys: []*Key{NewSecretKey(secret)}}
+}
+
+func (keyring *keyring) Signing() (*Key, error) {
+	now := time.Now().In(time.UTC)
+
+	var active *Key
+
+	for _, key := range keyring.keys {
+		if state := key.State(now); state == StatePending || state == StateRetired {
+			continue
+		}
+
+		if active == nil || key.ActivatedAt().After(active.ActivatedAt()) {
+			active = key
+		}
+	}
+
+	if active == nil {
+		return nil, NoActiveKeyError
+	}
+
+	return active, nil
+}
+
+func (keyring *keyring) Verifying(id string) (*Key, error) {
+	now := time.Now().In(time.UTC)
+
+	for _, key := range keyring.keys {
+		if key.ID == id && key.State(now) != StateRetired {
+			return key, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("keyring: key '%s' not found or retired", id))
+}
+
+func (keyring *keyring) Keys() []*Key {
+	return keyring.keys
+}
+
+// Rotate adding new key which signs tokens since activateAt, before it the key is pending and only verifies tokens,
+// so that every replica loads it first. The previous active and pending keys sign until activateAt
+// and verify tokens until retireAt, the already retired keys are removed. The key of keyring without signing key signs immediately
+func Rotate(keys []*Key, key *Key, activateAt time.Time, retireAt time.Time) []*Key {
+	now := time.Now().In(time.UTC)
+
+	rotated := make([]*Key, 0, len(keys)+1)
+	signing := false
+
+	for _, previous := range keys {
+		switch previous.State(now) {
+		case StateRetired:
+			continue
+		case StateActive:
+			signing = true
+			previous.RetireAt = &retireAt
+		case StatePending:
+			previous.RetireAt = &retireAt
+		case StateRetiring:
+			signing = true
+		}
+
+		rotated = append(rotated, previous)
+	}
+
+	if signing {
+		key.ActivateAt = &activateAt
+	}
+
+	return append(rotated, key)
+}
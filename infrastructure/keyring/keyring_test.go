@@ -0,0 +1,79 @@
+package keyring
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRotatedKeySignsAfterActivation(t *testing.T) {
+	now := time.Now().In(time.UTC)
+
+	first, second := generate(t), generate(t)
+	first.CreatedAt = now.Add(-time.Hour)
+
+	keys := Rotate([]*Key{first}, second, now.Add(time.Hour), now.Add(2*time.Hour))
+
+	tokenKeyring, err := NewKeyring(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key, err := tokenKeyring.Signing(); err != nil || key.ID != first.ID {
+		t.Fatalf("signing key %v, error %v, want previous '%s' before activation", key, err, first.ID)
+	}
+
+	if state := second.State(now); state != StatePending {
+		t.Fatalf("state of rotated key '%s', want '%s'", state, StatePending)
+	}
+
+	// the pending key verifies tokens of replicas which activated it earlier
+	if _, err := tokenKeyring.Verifying(second.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	activateAt := now.Add(-time.Second)
+	second.ActivateAt = &activateAt
+
+	if key, err := tokenKeyring.Signing(); err != nil || key.ID != second.ID {
+		t.Fatalf("signing key %v, error %v, want rotated '%s' after activation", key, err, second.ID)
+	}
+
+	if state := first.State(now); state != StateRetiring {
+		t.Fatalf("state of previous key '%s', want '%s'", state, StateRetiring)
+	}
+}
+
+func TestRotateActivatesFirstKey(t *testing.T) {
+	key := generate(t)
+
+	tokenKeyring, err := NewKeyring(Rotate(nil, key, time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signing, err := tokenKeyring.Signing(); err != nil || signing.ID != key.ID {
+		t.Fatalf("signing key %v, error %v, want the first key '%s'", signing, err, key.ID)
+	}
+}
+
+func TestRotateRetiresPendingKey(t *testing.T) {
+	now := time.Now().In(time.UTC)
+
+	first, second, third := generate(t), generate(t), generate(t)
+
+	keys := Rotate([]*Key{first}, second, now.Add(time.Hour), now.Add(2*time.Hour))
+	keys = Rotate(keys, third, now.Add(3*time.Hour), now.Add(4*time.Hour))
+
+	if second.RetireAt == nil || !second.RetireAt.Equal(now.Add(4*time.Hour)) {
+		t.Fatalf("pending key retires at %v, want %s", second.RetireAt, now.Add(4*time.Hour))
+	}
+
+	tokenKeyring, err := NewKeyring(keys...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key, err := tokenKeyring.Signing(); err != nil || key.ID != first.ID {
+		t.Fatalf("signing key %v, error %v, want '%s' before activations", key, err, first.ID)
+	}
+}
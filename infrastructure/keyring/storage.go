@@ -0,0 +1,80 @@
+package keyring
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Storage storage of keys of keyring
+type Storage interface {
+	// Load return keys of storage, the not existing storage has no keys
+	Load() ([]*Key, error)
+
+	// Save replacing keys of storage
+	Save(keys []*Key) error
+}
+
+// storageFile content of file of keyring
+type storageFile struct {
+	Keys []*Key `json:"keys"`
+}
+
+type file struct {
+	path string
+}
+
+// NewFile creating Storage in JSON file, the file is readable only by owner
+func NewFile(path string) Storage {
+	return &file{path: path}
+}
+
+func (storage *file) Load() ([]*Key, error) {
+	content, err := os.ReadFile(storage.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Unmarshal(content)
+}
+
+// Unmarshal decoding keys of content of file of keyring
+func Unmarshal(content []byte) ([]*Key, error) {
+	keys := &storageFile{}
+	if err := json.Unmarshal(content, keys); err != nil {
+		return nil, err
+	}
+
+	return keys.Keys, nil
+}
+
+// Save writing keys to temporary file and renaming it, so reader never sees partial file
+func (storage *file) Save(keys []*Key) error {
+	content, err := json.MarshalIndent(&storageFile{Keys: keys}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	temporary, err := os.CreateTemp(filepath.Dir(storage.path), filepath.Base(storage.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(temporary.Name())
+
+	if _, err := temporary.Write(content); err != nil {
+		_ = temporary.Close()
+
+		return err
+	}
+
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary.Name(), storage.path)
+}
@@ -0,0 +1,116 @@
+package keyring
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// MinimumSecretEntropy minimum estimated entropy of secret of HMAC in bits
+	MinimumSecretEntropy = 128
+	// MinimumRsaBits minimum size of RSA key
+	MinimumRsaBits = 2048
+)
+
+// Check checking strength of key, the error lists every weakness
+func (key *Key) Check() error {
+	var weaknesses []string
+
+	switch signKey := key.signKey.(type) {
+	case []byte:
+		if len(signKey) < hmacSize(key.Algorithm) {
+			weaknesses = append(weaknesses, fmt.Sprintf("secret is %d bytes, %s requires at least %d", len(signKey), key.Algorithm, hmacSize(key.Algorithm)))
+		}
+
+		if entropy := Entropy(signKey); entropy < MinimumSecretEntropy {
+			weaknesses = append(weaknesses, fmt.Sprintf("secret has about %.0f bits of entropy, at least %d required", entropy, MinimumSecretEntropy))
+		}
+	case *rsa.PrivateKey:
+		if bits := signKey.N.BitLen(); bits < MinimumRsaBits {
+			weaknesses = append(weaknesses, fmt.Sprintf("RSA key is %d bits, at least %d required", bits, MinimumRsaBits))
+		}
+	case nil:
+		weaknesses = append(weaknesses, "key is not parsed")
+	}
+
+	if len(weaknesses) == 0 {
+		return nil
+	}
+
+	if key.ID == "" {
+		return errors.New(fmt.Sprintf("keyring: secret of %s is weak: %s", key.Algorithm, strings.Join(weaknesses, "; ")))
+	}
+
+	return errors.New(fmt.Sprintf("keyring: key '%s' of %s is weak: %s", key.ID, key.Algorithm, strings.Join(weaknesses, "; ")))
+}
+
+// Entropy estimating entropy of secret in bits. Every symbol has at most bits of alphabet of secret,
+// the alphabet is the smaller of pool of classes of its symbols and number of its distinct symbols.
+// The symbol which repeats or continues sequence of the previous one, as in 'aaaa' or 'abcd',
+// and the symbol which ends already seen three symbols, as in 'passpass', have no entropy
+func Entropy(secret []byte) float64 {
+	if len(secret) == 0 {
+		return 0
+	}
+
+	distinct := map[byte]bool{}
+	classes := map[string]int{}
+
+	for _, symbol := range secret {
+		distinct[symbol] = true
+
+		switch {
+		case symbol >= 'a' && symbol <= 'z':
+			classes["lower"] = 26
+		case symbol >= 'A' && symbol <= 'Z':
+			classes["upper"] = 26
+		case symbol >= '0' && symbol <= '9':
+			classes["digit"] = 10
+		case symbol >= ' ' && symbol <= '~':
+			classes["punctuation"] = 33
+		default:
+			classes["binary"] = 256
+		}
+	}
+
+	pool := 0
+	for _, size := range classes {
+		pool += size
+	}
+
+	if _, exist := classes["binary"]; exist || pool > 256 {
+		pool = 256
+	}
+
+	alphabet := math.Min(float64(pool), float64(len(distinct)))
+	if alphabet < 2 {
+		return 0
+	}
+
+	perSymbol := math.Log2(alphabet)
+
+	seen := map[string]bool{}
+
+	entropy := perSymbol
+	for index := 1; index < len(secret); index++ {
+		if difference := int(secret[index]) - int(secret[index-1]); difference >= -1 && difference <= 1 {
+			continue
+		}
+
+		if index >= 2 {
+			trigram := string(secret[index-2 : index+1])
+			if seen[trigram] {
+				continue
+			}
+
+			seen[trigram] = true
+		}
+
+		entropy += perSymbol
+	}
+
+	return entropy
+}
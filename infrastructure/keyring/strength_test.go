@@ -0,0 +1,46 @@
+package keyring
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestEntropyRejectsWeakSecrets(t *testing.T) {
+	for _, secret := range []string{
+		"",
+		strings.Repeat("a", 64),
+		"abcdefghijklmnopqrstuvwxyz012345",
+		"zyxwvutsrqponmlkjihgfedcba543210",
+		strings.Repeat("0123456789", 8),
+		strings.Repeat("ab", 40),
+		strings.Repeat("password", 8),
+		"00112233445566778899aabbccddeeff",
+	} {
+		if entropy := Entropy([]byte(secret)); entropy >= MinimumSecretEntropy {
+			t.Fatalf("secret '%s' has %.0f bits of entropy, expected less than %d", secret, entropy, MinimumSecretEntropy)
+		}
+	}
+}
+
+func TestEntropyAcceptsGeneratedSecrets(t *testing.T) {
+	for attempt := 0; attempt < 100; attempt++ {
+		secret := make([]byte, 48)
+		if _, err := rand.Read(secret); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, generated := range [][]byte{secret[:32], []byte(base64.StdEncoding.EncodeToString(secret))} {
+			if entropy := Entropy(generated); entropy < MinimumSecretEntropy {
+				t.Fatalf("generated secret '%x' has %.0f bits of entropy, expected at least %d", generated, entropy, MinimumSecretEntropy)
+			}
+		}
+	}
+}
+
+func TestCheckRejectsSequentialSecret(t *testing.T) {
+	if err := NewSecretKey("abcdefghijklmnopqrstuvwxyz012345").Check(); err == nil {
+		t.Fatal("expected sequential secret to be weak")
+	}
+}
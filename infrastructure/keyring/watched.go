@@ -0,0 +1,96 @@
+package keyring
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"github.com/diez37/go-packages/log"
+	"github.com/diez37/go-packages/repeater"
+	"sync/atomic"
+)
+
+// Watched Keyring of source which is re-read by Process, the keyring is rebuilt only when the source changed
+// and the previous keyring is used while the changed source is invalid
+type Watched interface {
+	Keyring
+	repeater.Process
+}
+
+// watchedState keyring with checksum of its source, it's replaced as a whole
+type watchedState struct {
+	keyring  Keyring
+	checksum []byte
+}
+
+type watched struct {
+	name   string
+	read   func(ctx context.Context) ([]byte, error)
+	build  func(content []byte) (Keyring, error)
+	state  atomic.Value
+	logger log.Logger
+}
+
+// NewWatched creating Watched of source by name, read returns content of source and build creates keyring of content
+func NewWatched(
+	ctx context.Context,
+	name string,
+	read func(ctx context.Context) ([]byte, error),
+	build func(content []byte) (Keyring, error),
+	logger log.Logger,
+) (Watched, error) {
+	watched := &watched{name: name, read: read, build: build, logger: logger}
+
+	content, err := read(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keyring, err := build(content)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(content)
+	watched.state.Store(&watchedState{keyring: keyring, checksum: checksum[:]})
+
+	return watched, nil
+}
+
+func (watched *watched) Process(ctx context.Context) error {
+	content, err := watched.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	checksum := sha256.Sum256(content)
+	if bytes.Equal(checksum[:], watched.current().checksum) {
+		return nil
+	}
+
+	keyring, err := watched.build(content)
+	if err != nil {
+		return err
+	}
+
+	watched.state.Store(&watchedState{keyring: keyring, checksum: checksum[:]})
+
+	watched.logger.Infof("keyring: %s changed, keyring reloaded", watched.name)
+
+	return nil
+}
+
+func (watched *watched) Signing() (*Key, error) {
+	return watched.current().keyring.Signing()
+}
+
+func (watched *watched) Verifying(id string) (*Key, error) {
+	return watched.current().keyring.Verifying(id)
+}
+
+func (watched *watched) Keys() []*Key {
+	return watched.current().keyring.Keys()
+}
+
+func (watched *watched) current() *watchedState {
+	return watched.state.Load().(*watchedState)
+}
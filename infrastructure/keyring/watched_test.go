@@ -0,0 +1,122 @@
+package keyring
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/infrastructure/secret"
+	"github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newWatchedFile creating Watched of file of keyring which is read by provider of secrets
+func newWatchedFile(t *testing.T, path string) Watched {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	read := func(ctx context.Context) ([]byte, error) {
+		return secret.NewFile().Read(ctx, path)
+	}
+
+	build := func(content []byte) (Keyring, error) {
+		keys, err := Unmarshal(content)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewKeyring(keys...)
+	}
+
+	watched, err := NewWatched(context.Background(), path, read, build, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return watched
+}
+
+func generate(t *testing.T) *Key {
+	t.Helper()
+
+	key, err := Generate(AlgorithmHS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func TestWatchedRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	storage := NewFile(path)
+
+	first := generate(t)
+	if err := storage.Save([]*Key{first}); err != nil {
+		t.Fatal(err)
+	}
+
+	watched := newWatchedFile(t, path)
+
+	if key, err := watched.Signing(); err != nil || key.ID != first.ID {
+		t.Fatalf("signing key %v, error %v, want '%s'", key, err, first.ID)
+	}
+
+	second := generate(t)
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+
+	if err := storage.Save(Rotate([]*Key{first}, second, time.Now(), time.Now().Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := watched.Process(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if key, err := watched.Signing(); err != nil || key.ID != second.ID {
+		t.Fatalf("signing key %v, error %v, want rotated '%s'", key, err, second.ID)
+	}
+
+	// the previous key verifies tokens until it is retired
+	if _, err := watched.Verifying(first.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchedKeepsKeyringOfInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+
+	key := generate(t)
+	if err := NewFile(path).Save([]*Key{key}); err != nil {
+		t.Fatal(err)
+	}
+
+	watched := newWatchedFile(t, path)
+
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := watched.Process(context.Background()); err == nil {
+		t.Fatal("invalid keyring is accepted")
+	}
+
+	if signing, err := watched.Signing(); err != nil || signing.ID != key.ID {
+		t.Fatalf("signing key %v, error %v, want previous '%s'", signing, err, key.ID)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := watched.Process(context.Background()); err == nil {
+		t.Fatal("removed keyring is accepted")
+	}
+
+	if signing, err := watched.Signing(); err != nil || signing.ID != key.ID {
+		t.Fatalf("signing key %v, error %v, want previous '%s'", signing, err, key.ID)
+	}
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"github.com/diez37/go-packages/app"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics container for prometheus metrics of tokens
+type Metrics struct {
+	// BlockerPending number of tokens waiting for block in repository
+	BlockerPending prometheus.Gauge
+
+	// SaverBuffer number of tokens waiting for save in repository
+	SaverBuffer prometheus.Gauge
+
+	// BufferOverflow number of writes to full buffer by service and overflow action
+	BufferOverflow *prometheus.CounterVec
+
+	// TokenOperations number of operations with tokens by operation and outcome
+	TokenOperations *prometheus.CounterVec
+
+	// RefreshDenials number of denied refreshes by failed check
+	RefreshDenials *prometheus.CounterVec
+
+	// RepositoryDuration duration of calls of repository by operation
+	RepositoryDuration *prometheus.HistogramVec
+
+	// ProcessFailures number of failed runs of repeatable processes by process
+	ProcessFailures *prometheus.CounterVec
+}
+
+func NewMetrics(appConfig *app.Config) *Metrics {
+	return &Metrics{
+		BlockerPending: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "blocker_pending",
+			Help: "number of tokens waiting for block in repository",
+			ConstLabels: map[string]string{
+				"app": appConfig.Name,
+			},
+		}),
+		SaverBuffer: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "saver_buffer",
+			Help: "number of tokens waiting for save in repository",
+			ConstLabels: map[string]string{
+				"app": appConfig.Name,
+			},
+		}),
+		BufferOverflow: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "buffer_overflow_total",
+			Help: "number of writes to full buffer",
+			ConstLabels: map[string]string{
+				"app": appConfig.Name,
+			},
+		}, []string{"service", "action"}),
+		TokenOperations: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "token_operations_total",
+			Help: "number of operations with tokens",
+			ConstLabels: map[string]string{
+				"app": appConfig.Name,
+			},
+		}, []string{"operation", "outcome"}),
+		RefreshDenials: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "refresh_denials_total",
+			Help: "number of denied refreshes",
+			ConstLabels: map[string]string{
+				"app": appConfig.Name,
+			},
+		}, []string{"check"}),
+		RepositoryDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "repository_duration_seconds",
+			Help: "duration of calls of repository",
+			ConstLabels: map[string]string{
+				"app": appConfig.Name,
+			},
+		}, []string{"operation"}),
+		ProcessFailures: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "process_failures_total",
+			Help: "number of failed runs of repeatable processes",
+			ConstLabels: map[string]string{
+				"app": appConfig.Name,
+			},
+		}, []string{"process"}),
+	}
+}
@@ -0,0 +1,45 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaWriter writer of messages to topic of Kafka, it's implemented by kafka.Writer
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+type kafkaPublisher struct {
+	writer KafkaWriter
+}
+
+// NewKafka creating Publisher to Kafka, events of one login have the same key and keep order in partition
+func NewKafka(writer KafkaWriter) Publisher {
+	return &kafkaPublisher{writer: writer}
+}
+
+func (publisher *kafkaPublisher) Publish(ctx context.Context, delivery uuid.UUID, eventType string, payload []byte) error {
+	event := struct {
+		Login string `json:"login"`
+	}{}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return err
+	}
+
+	return publisher.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(event.Login),
+		Value: payload,
+		Headers: []kafka.Header{
+			{Key: DeliveryHeader, Value: []byte(delivery.String())},
+			{Key: EventHeader, Value: []byte(eventType)},
+		},
+	})
+}
+
+func (publisher *kafkaPublisher) Close() error {
+	return publisher.writer.Close()
+}
@@ -0,0 +1,89 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+	"testing"
+)
+
+// kafkaWriter fake of KafkaWriter which keeps written messages
+type kafkaWriter struct {
+	messages []kafka.Message
+	err      error
+	closed   bool
+}
+
+func (writer *kafkaWriter) WriteMessages(_ context.Context, messages ...kafka.Message) error {
+	if writer.err != nil {
+		return writer.err
+	}
+
+	writer.messages = append(writer.messages, messages...)
+
+	return nil
+}
+
+func (writer *kafkaWriter) Close() error {
+	writer.closed = true
+
+	return nil
+}
+
+func TestKafkaPublish(t *testing.T) {
+	writer := &kafkaWriter{}
+	publisher := NewKafka(writer)
+
+	delivery := uuid.New()
+	payload := []byte(`{"login":"3f1c1d4e-8a7b-4f52-9b39-2d0e6a1b7c55","type":"session.created"}`)
+
+	if err := publisher.Publish(context.Background(), delivery, "session.created", payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(writer.messages) != 1 {
+		t.Fatalf("%d messages, want 1", len(writer.messages))
+	}
+
+	message := writer.messages[0]
+
+	// the key is login, so events of one login keep order in partition
+	if string(message.Key) != "3f1c1d4e-8a7b-4f52-9b39-2d0e6a1b7c55" {
+		t.Fatalf("key '%s', want login", message.Key)
+	}
+
+	if string(message.Value) != string(payload) {
+		t.Fatalf("value '%s', want '%s'", message.Value, payload)
+	}
+
+	headers := map[string]string{}
+	for _, header := range message.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+
+	if headers[DeliveryHeader] != delivery.String() || headers[EventHeader] != "session.created" {
+		t.Fatalf("headers %v, want delivery '%s' and event 'session.created'", headers, delivery)
+	}
+
+	if err := publisher.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !writer.closed {
+		t.Fatal("writer is not closed")
+	}
+}
+
+func TestKafkaPublishError(t *testing.T) {
+	writeErr := errors.New("leader not available")
+	publisher := NewKafka(&kafkaWriter{err: writeErr})
+
+	if err := publisher.Publish(context.Background(), uuid.New(), "session.created", []byte(`{}`)); err != writeErr {
+		t.Fatalf("error %v, want %v", err, writeErr)
+	}
+
+	if err := publisher.Publish(context.Background(), uuid.New(), "session.created", []byte(`not json`)); err == nil {
+		t.Fatal("publish of invalid payload succeeded")
+	}
+}
@@ -0,0 +1,42 @@
+package publisher
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+type natsPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNats creating Publisher to NATS, event is published to subject 'subject.type' with headers of delivery and type
+func NewNats(conn *nats.Conn, subject string) Publisher {
+	return &natsPublisher{conn: conn, subject: subject}
+}
+
+func (publisher *natsPublisher) Publish(ctx context.Context, delivery uuid.UUID, eventType string, payload []byte) error {
+	message := nats.NewMsg(publisher.subject + "." + eventType)
+	message.Data = payload
+	message.Header.Set(DeliveryHeader, delivery.String())
+	message.Header.Set(EventHeader, eventType)
+
+	if err := publisher.conn.PublishMsg(message); err != nil {
+		return err
+	}
+
+	// the event leaves outbox after publish, flush confirms that server received it,
+	// flush with context requires deadline, without it the default timeout of connection is used
+	if _, exist := ctx.Deadline(); !exist {
+		return publisher.conn.Flush()
+	}
+
+	return publisher.conn.FlushWithContext(ctx)
+}
+
+func (publisher *natsPublisher) Close() error {
+	publisher.conn.Close()
+
+	return nil
+}
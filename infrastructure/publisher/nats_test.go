@@ -0,0 +1,120 @@
+package publisher
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"testing"
+	"time"
+)
+
+// runNatsServer running embedded NATS server on random port until end of test
+func runNatsServer(t *testing.T) *server.Server {
+	t.Helper()
+
+	natsServer, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go natsServer.Start()
+
+	if !natsServer.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server is not ready")
+	}
+
+	t.Cleanup(natsServer.Shutdown)
+
+	return natsServer
+}
+
+func TestNatsPublish(t *testing.T) {
+	natsServer := runNatsServer(t)
+
+	subscriber, err := nats.Connect(natsServer.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer subscriber.Close()
+
+	subscription, err := subscriber.SubscribeSync("tokenizer.events.>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := subscriber.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := nats.Connect(natsServer.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := NewNats(conn, "tokenizer.events")
+	defer publisher.Close()
+
+	delivery := uuid.New()
+	payload := []byte(`{"login":"login"}`)
+
+	if err := publisher.Publish(context.Background(), delivery, "session.created", payload); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Second)
+	defer cancelFunc()
+
+	if err := publisher.Publish(ctx, uuid.New(), "session.revoked", payload); err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := subscription.NextMsg(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message.Subject != "tokenizer.events.session.created" {
+		t.Fatalf("subject '%s', want 'tokenizer.events.session.created'", message.Subject)
+	}
+
+	if string(message.Data) != string(payload) {
+		t.Fatalf("payload '%s', want '%s'", message.Data, payload)
+	}
+
+	if header := message.Header.Get(DeliveryHeader); header != delivery.String() {
+		t.Fatalf("header of delivery '%s', want '%s'", header, delivery)
+	}
+
+	if header := message.Header.Get(EventHeader); header != "session.created" {
+		t.Fatalf("header of event '%s', want 'session.created'", header)
+	}
+
+	if message, err = subscription.NextMsg(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if message.Subject != "tokenizer.events.session.revoked" {
+		t.Fatalf("subject '%s', want 'tokenizer.events.session.revoked'", message.Subject)
+	}
+}
+
+func TestNatsPublishClosedConnection(t *testing.T) {
+	natsServer := runNatsServer(t)
+
+	conn, err := nats.Connect(natsServer.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher := NewNats(conn, "tokenizer.events")
+	if err := publisher.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the event stays in outbox when server did not confirm it
+	if err := publisher.Publish(context.Background(), uuid.New(), "session.created", []byte(`{}`)); err == nil {
+		t.Fatal("publish to closed connection succeeded")
+	}
+}
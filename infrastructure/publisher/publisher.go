@@ -0,0 +1,24 @@
+package publisher
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"io"
+)
+
+const (
+	StdoutPublisher = "stdout"
+	NatsPublisher   = "nats"
+	KafkaPublisher  = "kafka"
+
+	DeliveryHeader = "delivery"
+	EventHeader    = "event"
+)
+
+// Publisher sink of events, payload is json of event
+type Publisher interface {
+	io.Closer
+
+	// Publish sending payload of event, delivery is unique for every event and sink
+	Publish(ctx context.Context, delivery uuid.UUID, eventType string, payload []byte) error
+}
@@ -0,0 +1,43 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"io"
+	"sync"
+)
+
+type stdoutLine struct {
+	Delivery uuid.UUID       `json:"delivery"`
+	Type     string          `json:"type"`
+	Event    json.RawMessage `json:"event"`
+}
+
+type stdout struct {
+	mutex  *sync.Mutex
+	writer io.Writer
+}
+
+// NewStdout creating Publisher which writes events to writer as JSON lines, it's used for debugging
+func NewStdout(writer io.Writer) Publisher {
+	return &stdout{mutex: &sync.Mutex{}, writer: writer}
+}
+
+func (publisher *stdout) Publish(_ context.Context, delivery uuid.UUID, eventType string, payload []byte) error {
+	line, err := json.Marshal(&stdoutLine{Delivery: delivery, Type: eventType, Event: payload})
+	if err != nil {
+		return err
+	}
+
+	publisher.mutex.Lock()
+	defer publisher.mutex.Unlock()
+
+	_, err = publisher.writer.Write(append(line, '\n'))
+
+	return err
+}
+
+func (publisher *stdout) Close() error {
+	return nil
+}
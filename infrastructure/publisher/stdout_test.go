@@ -0,0 +1,38 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestStdoutPublish(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	publisher := NewStdout(buffer)
+
+	delivery := uuid.New()
+
+	if err := publisher.Publish(context.Background(), delivery, "session.created", []byte(`{"login":"login"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Publish(context.Background(), uuid.New(), "session.revoked", []byte(`{"login":"login"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("%d lines, want 2", len(lines))
+	}
+
+	line := &stdoutLine{}
+	if err := json.Unmarshal(lines[0], line); err != nil {
+		t.Fatal(err)
+	}
+
+	if line.Delivery != delivery || line.Type != "session.created" || string(line.Event) != `{"login":"login"}` {
+		t.Fatalf("line %s, want delivery '%s' of 'session.created'", lines[0], delivery)
+	}
+}
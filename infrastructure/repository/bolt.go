@@ -0,0 +1,534 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sort"
+	"time"
+)
+
+const (
+	BoltDriver = "bolt"
+
+	boltTokensBucketName       = "refresh_tokens"
+	boltLoginBucketName        = "refresh_tokens_login"
+	boltExpiresInBucketName    = "refresh_tokens_expires_in"
+	boltRevokedAtBucketName    = "refresh_tokens_revoked_at"
+	boltArchiveBucketName      = "refresh_tokens_archive"
+	boltArchivedAtBucketName   = "refresh_tokens_archive_archived_at"
+	boltArchiveLoginBucketName = "refresh_tokens_archive_login"
+
+	boltTimeLength = 8
+)
+
+type bolt struct {
+	db     *bbolt.DB
+	tracer trace.Tracer
+}
+
+// NewBolt creating Repository on top of embedded key-value storage, tokens are stored by uuid
+// with secondary indexes 'login + created_at + uuid', 'expires_in + uuid' and 'revoked_at + uuid',
+// archived tokens are stored by uuid with indexes 'archived_at + uuid' and 'login + created_at + uuid'
+func NewBolt(db *bbolt.DB, tracer trace.Tracer) (Repository, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		buckets := []string{
+			boltTokensBucketName,
+			boltLoginBucketName,
+			boltExpiresInBucketName,
+			boltRevokedAtBucketName,
+			boltArchiveBucketName,
+			boltArchivedAtBucketName,
+			boltArchiveLoginBucketName,
+			boltOutboxBucketName,
+			boltOutboxSendAtBucketName,
+		}
+
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bolt{db: db, tracer: tracer}, nil
+}
+
+func (repository *bolt) FindByLogin(ctx context.Context, login uuid.UUID) ([]*RefreshToken, error) {
+	_, span := repository.tracer.Start(ctx, "finder.login")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("login", login.String()),
+		attribute.String("repository", BoltDriver),
+	)
+
+	var refreshTokens []*RefreshToken
+
+	err := repository.db.View(func(tx *bbolt.Tx) error {
+		tokens := tx.Bucket([]byte(boltTokensBucketName))
+		cursor := tx.Bucket([]byte(boltLoginBucketName)).Cursor()
+
+		for key, _ := cursor.Seek(login[:]); key != nil && bytes.HasPrefix(key, login[:]); key, _ = cursor.Next() {
+			refreshToken, err := boltDecode(tokens.Get(key[len(key)-len(uuid.UUID{}):]))
+			if err != nil {
+				return err
+			}
+
+			if refreshToken.RevokedAt != nil {
+				continue
+			}
+
+			refreshTokens = append(refreshTokens, refreshToken)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(refreshTokens) == 0 {
+		return nil, db.RecordNotFoundError
+	}
+
+	return refreshTokens, nil
+}
+
+func (repository *bolt) FindByUUID(ctx context.Context, uuid uuid.UUID) (*RefreshToken, error) {
+	_, span := repository.tracer.Start(ctx, "finder.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", BoltDriver),
+	)
+
+	var refreshToken *RefreshToken
+
+	err := repository.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket([]byte(boltTokensBucketName)).Get(uuid[:])
+		if value == nil {
+			return db.RecordNotFoundError
+		}
+
+		var err error
+		if refreshToken, err = boltDecode(value); err != nil {
+			return err
+		}
+
+		if refreshToken.RevokedAt != nil {
+			return db.RecordNotFoundError
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refreshToken, nil
+}
+
+func (repository *bolt) FindRevokedByUUID(ctx context.Context, uuid uuid.UUID) (*RefreshToken, error) {
+	_, span := repository.tracer.Start(ctx, "finder.revoked.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", BoltDriver),
+	)
+
+	var refreshToken *RefreshToken
+
+	err := repository.db.View(func(tx *bbolt.Tx) error {
+		if value := tx.Bucket([]byte(boltTokensBucketName)).Get(uuid[:]); value != nil {
+			var err error
+			if refreshToken, err = boltDecode(value); err != nil {
+				return err
+			}
+
+			if refreshToken.RevokedAt == nil {
+				return db.RecordNotFoundError
+			}
+
+			return nil
+		}
+
+		value := tx.Bucket([]byte(boltArchiveBucketName)).Get(uuid[:])
+		if value == nil {
+			return db.RecordNotFoundError
+		}
+
+		archivedToken := &ArchivedToken{}
+		if err := json.Unmarshal(value, archivedToken); err != nil {
+			return err
+		}
+
+		refreshToken = &archivedToken.RefreshToken
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refreshToken, nil
+}
+
+func (repository *bolt) FindAllByLogin(ctx context.Context, login uuid.UUID) ([]*RefreshToken, error) {
+	_, span := repository.tracer.Start(ctx, "finder.all.login")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("login", login.String()),
+		attribute.String("repository", BoltDriver),
+	)
+
+	var refreshTokens []*RefreshToken
+
+	err := repository.db.View(func(tx *bbolt.Tx) error {
+		tokens := tx.Bucket([]byte(boltTokensBucketName))
+		cursor := tx.Bucket([]byte(boltLoginBucketName)).Cursor()
+
+		for key, _ := cursor.Seek(login[:]); key != nil && bytes.HasPrefix(key, login[:]); key, _ = cursor.Next() {
+			refreshToken, err := boltDecode(tokens.Get(key[len(key)-len(uuid.UUID{}):]))
+			if err != nil {
+				return err
+			}
+
+			refreshTokens = append(refreshTokens, refreshToken)
+		}
+
+		archive := tx.Bucket([]byte(boltArchiveBucketName))
+		cursor = tx.Bucket([]byte(boltArchiveLoginBucketName)).Cursor()
+
+		for key, _ := cursor.Seek(login[:]); key != nil && bytes.HasPrefix(key, login[:]); key, _ = cursor.Next() {
+			value := archive.Get(key[len(key)-len(uuid.UUID{}):])
+			if value == nil {
+				return errors.New("bolt: index refers to missing archived token")
+			}
+
+			archivedToken := &ArchivedToken{}
+			if err := json.Unmarshal(value, archivedToken); err != nil {
+				return err
+			}
+
+			refreshTokens = append(refreshTokens, &archivedToken.RefreshToken)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(refreshTokens) == 0 {
+		return nil, db.RecordNotFoundError
+	}
+
+	sort.SliceStable(refreshTokens, func(i, j int) bool {
+		return refreshTokens[i].CreatedAt.Before(refreshTokens[j].CreatedAt)
+	})
+
+	return refreshTokens, nil
+}
+
+func (repository *bolt) Insert(ctx context.Context, tokens ...*RefreshToken) error {
+	_, span := repository.tracer.Start(ctx, "saver.insert")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("length", len(tokens)),
+		attribute.String("repository", BoltDriver),
+	)
+
+	now := time.Now().In(time.UTC)
+
+	return repository.db.Update(func(tx *bbolt.Tx) error {
+		tokensBucket := tx.Bucket([]byte(boltTokensBucketName))
+		loginBucket := tx.Bucket([]byte(boltLoginBucketName))
+		expiresInBucket := tx.Bucket([]byte(boltExpiresInBucketName))
+
+		for _, token := range tokens {
+			if tokensBucket.Get(token.UUID[:]) != nil {
+				return errors.New(fmt.Sprintf("bolt: token '%s' already exists", token.UUID.String()))
+			}
+
+			token.CreatedAt = now
+
+			value, err := json.Marshal(token)
+			if err != nil {
+				return err
+			}
+
+			if err := tokensBucket.Put(token.UUID[:], value); err != nil {
+				return err
+			}
+
+			if err := loginBucket.Put(boltLoginKey(token), nil); err != nil {
+				return err
+			}
+
+			if err := expiresInBucket.Put(boltExpiresInKey(token), nil); err != nil {
+				return err
+			}
+		}
+
+		return boltOutboxPush(tx, EventsFromContext(ctx)...)
+	})
+}
+
+func (repository *bolt) BlockByUUID(ctx context.Context, reason RevokeReason, uuids ...uuid.UUID) error {
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	_, span := repository.tracer.Start(ctx, "blocker.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("length", len(uuids)),
+		attribute.String("reason", string(reason)),
+		attribute.String("repository", BoltDriver),
+	)
+
+	now := time.Now().In(time.UTC)
+
+	return repository.db.Update(func(tx *bbolt.Tx) error {
+		tokens := tx.Bucket([]byte(boltTokensBucketName))
+
+		for _, uuid := range uuids {
+			value := tokens.Get(uuid[:])
+			if value == nil {
+				continue
+			}
+
+			token, err := boltDecode(value)
+			if err != nil {
+				return err
+			}
+
+			if token.RevokedAt != nil {
+				continue
+			}
+
+			if err := boltRevoke(tx, token, now, reason); err != nil {
+				return err
+			}
+		}
+
+		return boltOutboxPush(tx, EventsFromContext(ctx)...)
+	})
+}
+
+func (repository *bolt) BlockByDate(ctx context.Context, date time.Time) error {
+	_, span := repository.tracer.Start(ctx, "blocker.date")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("repository", BoltDriver))
+
+	until := boltTime(date)
+
+	return repository.db.Update(func(tx *bbolt.Tx) error {
+		tokens := tx.Bucket([]byte(boltTokensBucketName))
+
+		var expired []*RefreshToken
+
+		cursor := tx.Bucket([]byte(boltExpiresInBucketName)).Cursor()
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key[:boltTimeLength], until) <= 0; key, _ = cursor.Next() {
+			token, err := boltDecode(tokens.Get(key[boltTimeLength:]))
+			if err != nil {
+				return err
+			}
+
+			if token.RevokedAt == nil {
+				expired = append(expired, token)
+			}
+		}
+
+		for _, token := range expired {
+			if err := boltRevoke(tx, token, token.ExpiresIn, RevokeReasonExpired); err != nil {
+				return err
+			}
+		}
+
+		var revoked []*RefreshToken
+
+		cursor = tx.Bucket([]byte(boltRevokedAtBucketName)).Cursor()
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key[:boltTimeLength], until) <= 0; key, _ = cursor.Next() {
+			token, err := boltDecode(tokens.Get(key[boltTimeLength:]))
+			if err != nil {
+				return err
+			}
+
+			revoked = append(revoked, token)
+		}
+
+		archive := tx.Bucket([]byte(boltArchiveBucketName))
+		archivedAt := tx.Bucket([]byte(boltArchivedAtBucketName))
+		archiveLogin := tx.Bucket([]byte(boltArchiveLoginBucketName))
+
+		for _, token := range revoked {
+			value, err := json.Marshal(&ArchivedToken{RefreshToken: *token, ArchivedAt: date})
+			if err != nil {
+				return err
+			}
+
+			if err := archive.Put(token.UUID[:], value); err != nil {
+				return err
+			}
+
+			if err := archivedAt.Put(boltArchiveKey(date, token), nil); err != nil {
+				return err
+			}
+
+			if err := archiveLogin.Put(boltLoginKey(token), nil); err != nil {
+				return err
+			}
+
+			if err := boltDelete(tx, token); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repository *bolt) Purge(ctx context.Context, date time.Time) error {
+	_, span := repository.tracer.Start(ctx, "archiver.purge")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("repository", BoltDriver))
+
+	until := boltTime(date)
+
+	return repository.db.Update(func(tx *bbolt.Tx) error {
+		archive := tx.Bucket([]byte(boltArchiveBucketName))
+		archivedAt := tx.Bucket([]byte(boltArchivedAtBucketName))
+		archiveLogin := tx.Bucket([]byte(boltArchiveLoginBucketName))
+
+		var keys [][]byte
+
+		cursor := archivedAt.Cursor()
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key[:boltTimeLength], until) <= 0; key, _ = cursor.Next() {
+			keys = append(keys, append([]byte{}, key...))
+		}
+
+		for _, key := range keys {
+			if value := archive.Get(key[boltTimeLength:]); value != nil {
+				archivedToken := &ArchivedToken{}
+				if err := json.Unmarshal(value, archivedToken); err != nil {
+					return err
+				}
+
+				if err := archiveLogin.Delete(boltLoginKey(&archivedToken.RefreshToken)); err != nil {
+					return err
+				}
+			}
+
+			if err := archive.Delete(key[boltTimeLength:]); err != nil {
+				return err
+			}
+
+			if err := archivedAt.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// boltRevoke marking token as revoked with index entry 'revoked_at + uuid'
+func boltRevoke(tx *bbolt.Tx, token *RefreshToken, revokedAt time.Time, reason RevokeReason) error {
+	token.RevokedAt, token.RevokeReason = &revokedAt, reason
+
+	value, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Bucket([]byte(boltTokensBucketName)).Put(token.UUID[:], value); err != nil {
+		return err
+	}
+
+	return tx.Bucket([]byte(boltRevokedAtBucketName)).Put(boltArchiveKey(revokedAt, token), nil)
+}
+
+// boltDelete removing token with all index entries
+func boltDelete(tx *bbolt.Tx, token *RefreshToken) error {
+	if err := tx.Bucket([]byte(boltTokensBucketName)).Delete(token.UUID[:]); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket([]byte(boltLoginBucketName)).Delete(boltLoginKey(token)); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket([]byte(boltExpiresInBucketName)).Delete(boltExpiresInKey(token)); err != nil {
+		return err
+	}
+
+	if token.RevokedAt == nil {
+		return nil
+	}
+
+	return tx.Bucket([]byte(boltRevokedAtBucketName)).Delete(boltArchiveKey(*token.RevokedAt, token))
+}
+
+func boltDecode(value []byte) (*RefreshToken, error) {
+	if value == nil {
+		return nil, errors.New("bolt: index refers to missing token")
+	}
+
+	token := &RefreshToken{}
+
+	return token, json.Unmarshal(value, token)
+}
+
+// boltLoginKey 'login + created_at + uuid', sorted by created_at inside login
+func boltLoginKey(token *RefreshToken) []byte {
+	key := make([]byte, 0, len(token.Login)+boltTimeLength+len(token.UUID))
+	key = append(key, token.Login[:]...)
+	key = append(key, boltTime(token.CreatedAt)...)
+
+	return append(key, token.UUID[:]...)
+}
+
+// boltExpiresInKey 'expires_in + uuid', sorted by expires_in
+func boltExpiresInKey(token *RefreshToken) []byte {
+	key := make([]byte, 0, boltTimeLength+len(token.UUID))
+	key = append(key, boltTime(token.ExpiresIn)...)
+
+	return append(key, token.UUID[:]...)
+}
+
+// boltArchiveKey 'date + uuid', used for revoked_at and archived_at, sorted by date
+func boltArchiveKey(date time.Time, token *RefreshToken) []byte {
+	key := make([]byte, 0, boltTimeLength+len(token.UUID))
+	key = append(key, boltTime(date)...)
+
+	return append(key, token.UUID[:]...)
+}
+
+func boltTime(date time.Time) []byte {
+	key := make([]byte, boltTimeLength)
+	binary.BigEndian.PutUint64(key, uint64(date.UnixNano()))
+
+	return key
+}
@@ -0,0 +1,456 @@
+// Package conformance contains the behaviour every repository.Repository implementation must follow,
+// implementations run it from their own tests with a factory of empty repositories
+package conformance
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+// Factory creating new empty repository for one test case
+type Factory func(t *testing.T) repository.Repository
+
+// Run running all cases of the suite against repositories from factory
+func Run(t *testing.T, factory Factory) {
+	cases := map[string]func(t *testing.T, tokenRepository repository.Repository){
+		"FindByLoginNotFound":   findByLoginNotFound,
+		"FindByUUIDNotFound":    findByUUIDNotFound,
+		"InsertAndFind":         insertAndFind,
+		"InsertSetsCreatedAt":   insertSetsCreatedAt,
+		"InsertDuplicate":       insertDuplicate,
+		"FindByLoginOrder":      findByLoginOrder,
+		"BlockByUUIDEmpty":      blockByUUIDEmpty,
+		"BlockByUUID":           blockByUUID,
+		"BlockByUUIDUnknown":    blockByUUIDUnknown,
+		"BlockByDateInclusive":  blockByDateInclusive,
+		"BlockByDateNotExpired": blockByDateNotExpired,
+		"BlockByDateArchives":   blockByDateArchives,
+		"PurgeKeepsTokens":      purgeKeepsTokens,
+		"FindRevokedByUUID":     findRevokedByUUID,
+		"FindRevokedArchived":   findRevokedArchived,
+		"FindRevokedNotRevoked": findRevokedNotRevoked,
+		"FindAllByLogin":        findAllByLogin,
+	}
+
+	for name, test := range cases {
+		test := test
+
+		t.Run(name, func(t *testing.T) {
+			test(t, factory(t))
+		})
+	}
+}
+
+func findByLoginNotFound(t *testing.T, tokenRepository repository.Repository) {
+	tokens, err := tokenRepository.FindByLogin(context.Background(), uuid.New())
+	if err != db.RecordNotFoundError {
+		t.Fatalf("FindByLogin: expected db.RecordNotFoundError, got '%v'", err)
+	}
+
+	if len(tokens) != 0 {
+		t.Fatalf("FindByLogin: expected no tokens, got %d", len(tokens))
+	}
+}
+
+func findByUUIDNotFound(t *testing.T, tokenRepository repository.Repository) {
+	token, err := tokenRepository.FindByUUID(context.Background(), uuid.New())
+	if err != db.RecordNotFoundError {
+		t.Fatalf("FindByUUID: expected db.RecordNotFoundError, got '%v'", err)
+	}
+
+	if token != nil {
+		t.Fatalf("FindByUUID: expected nil token, got '%s'", token.UUID)
+	}
+}
+
+func insertAndFind(t *testing.T, tokenRepository repository.Repository) {
+	ctx := context.Background()
+	token := newToken(uuid.New(), time.Hour)
+
+	insert(t, tokenRepository, token)
+
+	found, err := tokenRepository.FindByUUID(ctx, token.UUID)
+	if err != nil {
+		t.Fatalf("FindByUUID: %s", err)
+	}
+
+	assertEqual(t, token, found)
+
+	tokens, err := tokenRepository.FindByLogin(ctx, token.Login)
+	if err != nil {
+		t.Fatalf("FindByLogin: %s", err)
+	}
+
+	if len(tokens) != 1 {
+		t.Fatalf("FindByLogin: expected 1 token, got %d", len(tokens))
+	}
+
+	assertEqual(t, token, tokens[0])
+}
+
+func insertSetsCreatedAt(t *testing.T, tokenRepository repository.Repository) {
+	token := newToken(uuid.New(), time.Hour)
+	token.CreatedAt = time.Time{}
+
+	before := time.Now().In(time.UTC).Add(-time.Second)
+
+	insert(t, tokenRepository, token)
+
+	found, err := tokenRepository.FindByUUID(context.Background(), token.UUID)
+	if err != nil {
+		t.Fatalf("FindByUUID: %s", err)
+	}
+
+	if found.CreatedAt.Before(before) {
+		t.Fatalf("Insert: expected created_at set to now, got '%s'", found.CreatedAt)
+	}
+}
+
+func insertDuplicate(t *testing.T, tokenRepository repository.Repository) {
+	token := newToken(uuid.New(), time.Hour)
+
+	insert(t, tokenRepository, token)
+
+	duplicate := newToken(token.Login, time.Hour)
+	duplicate.UUID = token.UUID
+
+	if err := tokenRepository.Insert(context.Background(), duplicate); err == nil {
+		t.Fatal("Insert: expected error on duplicate uuid")
+	}
+}
+
+func findByLoginOrder(t *testing.T, tokenRepository repository.Repository) {
+	login := uuid.New()
+	tokens := []*repository.RefreshToken{
+		newToken(login, time.Hour),
+		newToken(login, time.Hour),
+		newToken(login, time.Hour),
+	}
+
+	for _, token := range tokens {
+		insert(t, tokenRepository, token)
+
+		// created_at is the sort key, separate inserts must not share it
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	insert(t, tokenRepository, newToken(uuid.New(), time.Hour))
+
+	found, err := tokenRepository.FindByLogin(context.Background(), login)
+	if err != nil {
+		t.Fatalf("FindByLogin: %s", err)
+	}
+
+	if len(found) != len(tokens) {
+		t.Fatalf("FindByLogin: expected %d tokens, got %d", len(tokens), len(found))
+	}
+
+	for index, token := range tokens {
+		if found[index].UUID != token.UUID {
+			t.Fatalf("FindByLogin: expected '%s' at %d, got '%s'", token.UUID, index, found[index].UUID)
+		}
+	}
+}
+
+func blockByUUIDEmpty(t *testing.T, tokenRepository repository.Repository) {
+	token := newToken(uuid.New(), time.Hour)
+
+	insert(t, tokenRepository, token)
+
+	if err := tokenRepository.BlockByUUID(context.Background(), repository.RevokeReasonLogout); err != nil {
+		t.Fatalf("BlockByUUID: %s", err)
+	}
+
+	assertExists(t, tokenRepository, token)
+}
+
+func blockByUUID(t *testing.T, tokenRepository repository.Repository) {
+	login := uuid.New()
+	blocked := newToken(login, time.Hour)
+	kept := newToken(login, time.Hour)
+
+	insert(t, tokenRepository, blocked, kept)
+
+	if err := tokenRepository.BlockByUUID(context.Background(), repository.RevokeReasonLogout, blocked.UUID); err != nil {
+		t.Fatalf("BlockByUUID: %s", err)
+	}
+
+	assertNotExists(t, tokenRepository, blocked)
+	assertExists(t, tokenRepository, kept)
+
+	tokens, err := tokenRepository.FindByLogin(context.Background(), login)
+	if err != nil {
+		t.Fatalf("FindByLogin: %s", err)
+	}
+
+	if len(tokens) != 1 || tokens[0].UUID != kept.UUID {
+		t.Fatalf("FindByLogin: expected only '%s' after block", kept.UUID)
+	}
+}
+
+func blockByUUIDUnknown(t *testing.T, tokenRepository repository.Repository) {
+	token := newToken(uuid.New(), time.Hour)
+
+	insert(t, tokenRepository, token)
+
+	if err := tokenRepository.BlockByUUID(context.Background(), repository.RevokeReasonLogout, uuid.New()); err != nil {
+		t.Fatalf("BlockByUUID: %s", err)
+	}
+
+	assertExists(t, tokenRepository, token)
+}
+
+func blockByDateInclusive(t *testing.T, tokenRepository repository.Repository) {
+	date := time.Now().In(time.UTC).Truncate(time.Second)
+
+	expired := newToken(uuid.New(), 0)
+	expired.ExpiresIn = date.Add(-time.Hour)
+
+	boundary := newToken(uuid.New(), 0)
+	boundary.ExpiresIn = date
+
+	insert(t, tokenRepository, expired, boundary)
+
+	if err := tokenRepository.BlockByDate(context.Background(), date); err != nil {
+		t.Fatalf("BlockByDate: %s", err)
+	}
+
+	assertNotExists(t, tokenRepository, expired)
+	assertNotExists(t, tokenRepository, boundary)
+}
+
+func blockByDateNotExpired(t *testing.T, tokenRepository repository.Repository) {
+	date := time.Now().In(time.UTC).Truncate(time.Second)
+
+	token := newToken(uuid.New(), 0)
+	token.ExpiresIn = date.Add(time.Second)
+
+	insert(t, tokenRepository, token)
+
+	if err := tokenRepository.BlockByDate(context.Background(), date); err != nil {
+		t.Fatalf("BlockByDate: %s", err)
+	}
+
+	assertExists(t, tokenRepository, token)
+}
+
+func blockByDateArchives(t *testing.T, tokenRepository repository.Repository) {
+	ctx := context.Background()
+	login := uuid.New()
+	blocked := newToken(login, time.Hour)
+	kept := newToken(login, time.Hour)
+
+	insert(t, tokenRepository, blocked, kept)
+
+	if err := tokenRepository.BlockByUUID(ctx, repository.RevokeReasonLogout, blocked.UUID); err != nil {
+		t.Fatalf("BlockByUUID: %s", err)
+	}
+
+	// the second sweep must not archive the same token again
+	for range []int{1, 2} {
+		if err := tokenRepository.BlockByDate(ctx, time.Now().In(time.UTC).Add(time.Second)); err != nil {
+			t.Fatalf("BlockByDate: %s", err)
+		}
+	}
+
+	assertNotExists(t, tokenRepository, blocked)
+	assertExists(t, tokenRepository, kept)
+}
+
+func purgeKeepsTokens(t *testing.T, tokenRepository repository.Repository) {
+	ctx := context.Background()
+	blocked := newToken(uuid.New(), time.Hour)
+	kept := newToken(uuid.New(), time.Hour)
+
+	insert(t, tokenRepository, blocked, kept)
+
+	if err := tokenRepository.BlockByUUID(ctx, repository.RevokeReasonLogout, blocked.UUID); err != nil {
+		t.Fatalf("BlockByUUID: %s", err)
+	}
+
+	if err := tokenRepository.BlockByDate(ctx, time.Now().In(time.UTC).Add(time.Second)); err != nil {
+		t.Fatalf("BlockByDate: %s", err)
+	}
+
+	if err := tokenRepository.Purge(ctx, time.Now().In(time.UTC).Add(time.Hour)); err != nil {
+		t.Fatalf("Purge: %s", err)
+	}
+
+	assertNotExists(t, tokenRepository, blocked)
+	assertExists(t, tokenRepository, kept)
+}
+
+func findRevokedByUUID(t *testing.T, tokenRepository repository.Repository) {
+	token := newToken(uuid.New(), time.Hour)
+
+	insert(t, tokenRepository, token)
+
+	if err := tokenRepository.BlockByUUID(context.Background(), repository.RevokeReasonRotated, token.UUID); err != nil {
+		t.Fatalf("BlockByUUID: %s", err)
+	}
+
+	assertRevoked(t, tokenRepository, token, repository.RevokeReasonRotated)
+}
+
+func findRevokedArchived(t *testing.T, tokenRepository repository.Repository) {
+	ctx := context.Background()
+	token := newToken(uuid.New(), time.Hour)
+
+	insert(t, tokenRepository, token)
+
+	if err := tokenRepository.BlockByUUID(ctx, repository.RevokeReasonViolation, token.UUID); err != nil {
+		t.Fatalf("BlockByUUID: %s", err)
+	}
+
+	if err := tokenRepository.BlockByDate(ctx, time.Now().In(time.UTC).Add(time.Second)); err != nil {
+		t.Fatalf("BlockByDate: %s", err)
+	}
+
+	assertRevoked(t, tokenRepository, token, repository.RevokeReasonViolation)
+}
+
+func findRevokedNotRevoked(t *testing.T, tokenRepository repository.Repository) {
+	token := newToken(uuid.New(), time.Hour)
+
+	insert(t, tokenRepository, token)
+
+	for _, uuid := range []uuid.UUID{token.UUID, uuid.New()} {
+		if _, err := tokenRepository.FindRevokedByUUID(context.Background(), uuid); err != db.RecordNotFoundError {
+			t.Fatalf("FindRevokedByUUID: expected db.RecordNotFoundError for '%s', got '%v'", uuid, err)
+		}
+	}
+}
+
+func findAllByLogin(t *testing.T, tokenRepository repository.Repository) {
+	ctx := context.Background()
+	login := uuid.New()
+	archived, revoked, actual := newToken(login, time.Hour), newToken(login, time.Hour), newToken(login, time.Hour)
+
+	insert(t, tokenRepository, archived, newToken(uuid.New(), time.Hour))
+
+	if err := tokenRepository.BlockByUUID(ctx, repository.RevokeReasonViolation, archived.UUID); err != nil {
+		t.Fatalf("BlockByUUID: %s", err)
+	}
+
+	if err := tokenRepository.BlockByDate(ctx, time.Now().In(time.UTC).Add(time.Second)); err != nil {
+		t.Fatalf("BlockByDate: %s", err)
+	}
+
+	insert(t, tokenRepository, revoked, actual)
+
+	if err := tokenRepository.BlockByUUID(ctx, repository.RevokeReasonLogout, revoked.UUID); err != nil {
+		t.Fatalf("BlockByUUID: %s", err)
+	}
+
+	tokens, err := tokenRepository.FindAllByLogin(ctx, login)
+	if err != nil {
+		t.Fatalf("FindAllByLogin: %s", err)
+	}
+
+	if len(tokens) != 3 {
+		t.Fatalf("FindAllByLogin: expected 3 tokens, got %d", len(tokens))
+	}
+
+	reasons := map[uuid.UUID]repository.RevokeReason{}
+	for index, token := range tokens {
+		if token.Login != login {
+			t.Fatalf("FindAllByLogin: expected login '%s', got '%s'", login, token.Login)
+		}
+
+		if index > 0 && token.CreatedAt.Before(tokens[index-1].CreatedAt) {
+			t.Fatalf("FindAllByLogin: expected tokens ordered by created_at")
+		}
+
+		reasons[token.UUID] = token.RevokeReason
+	}
+
+	expected := map[uuid.UUID]repository.RevokeReason{
+		archived.UUID: repository.RevokeReasonViolation,
+		revoked.UUID:  repository.RevokeReasonLogout,
+		actual.UUID:   "",
+	}
+
+	for uuid, reason := range expected {
+		if found, exist := reasons[uuid]; !exist || found != reason {
+			t.Fatalf("FindAllByLogin: expected token '%s' with reason '%s', got '%s'", uuid, reason, found)
+		}
+	}
+
+	if _, err := tokenRepository.FindAllByLogin(ctx, uuid.New()); err != db.RecordNotFoundError {
+		t.Fatalf("FindAllByLogin: expected db.RecordNotFoundError, got '%v'", err)
+	}
+}
+
+func assertRevoked(t *testing.T, tokenRepository repository.Repository, token *repository.RefreshToken, reason repository.RevokeReason) {
+	t.Helper()
+
+	found, err := tokenRepository.FindRevokedByUUID(context.Background(), token.UUID)
+	if err != nil {
+		t.Fatalf("FindRevokedByUUID: %s", err)
+	}
+
+	if found.Login != token.Login || found.RevokedAt == nil || found.RevokeReason != reason {
+		t.Fatalf("FindRevokedByUUID: expected token of '%s' revoked by '%s', got '%+v'", token.Login, reason, found)
+	}
+}
+
+func newToken(login uuid.UUID, lifetime time.Duration) *repository.RefreshToken {
+	now := time.Now().In(time.UTC)
+
+	return &repository.RefreshToken{
+		UUID:        uuid.New(),
+		Login:       login,
+		Ip:          "127.0.0.1",
+		Fingerprint: "fingerprint",
+		UserAgent:   "conformance",
+		CreatedAt:   now,
+		ExpiresIn:   now.Add(lifetime).Truncate(time.Second),
+	}
+}
+
+func insert(t *testing.T, tokenRepository repository.Repository, tokens ...*repository.RefreshToken) {
+	t.Helper()
+
+	if err := tokenRepository.Insert(context.Background(), tokens...); err != nil {
+		t.Fatalf("Insert: %s", err)
+	}
+}
+
+func assertExists(t *testing.T, tokenRepository repository.Repository, token *repository.RefreshToken) {
+	t.Helper()
+
+	if _, err := tokenRepository.FindByUUID(context.Background(), token.UUID); err != nil {
+		t.Fatalf("FindByUUID: expected token '%s', got '%v'", token.UUID, err)
+	}
+}
+
+func assertNotExists(t *testing.T, tokenRepository repository.Repository, token *repository.RefreshToken) {
+	t.Helper()
+
+	if _, err := tokenRepository.FindByUUID(context.Background(), token.UUID); err != db.RecordNotFoundError {
+		t.Fatalf("FindByUUID: expected token '%s' blocked, got '%v'", token.UUID, err)
+	}
+}
+
+func assertEqual(t *testing.T, expected, actual *repository.RefreshToken) {
+	t.Helper()
+
+	switch {
+	case expected.UUID != actual.UUID:
+		t.Fatalf("uuid: expected '%s', got '%s'", expected.UUID, actual.UUID)
+	case expected.Login != actual.Login:
+		t.Fatalf("login: expected '%s', got '%s'", expected.Login, actual.Login)
+	case expected.Ip != actual.Ip:
+		t.Fatalf("ip: expected '%s', got '%s'", expected.Ip, actual.Ip)
+	case expected.Fingerprint != actual.Fingerprint:
+		t.Fatalf("fingerprint: expected '%s', got '%s'", expected.Fingerprint, actual.Fingerprint)
+	case expected.UserAgent != actual.UserAgent:
+		t.Fatalf("user_agent: expected '%s', got '%s'", expected.UserAgent, actual.UserAgent)
+	case !expected.ExpiresIn.Equal(actual.ExpiresIn):
+		t.Fatalf("expires_in: expected '%s', got '%s'", expected.ExpiresIn, actual.ExpiresIn)
+	}
+}
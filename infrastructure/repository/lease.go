@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+)
+
+// Lease exclusive right of one holder for named work, replicas share it through storage
+type Lease interface {
+	// Acquire taking or extending lease until now + ttl, it returns false when lease belongs to other holder and not expired
+	Acquire(ctx context.Context, name string, holder uuid.UUID, ttl time.Duration) (bool, error)
+}
+
+type memoryLeaseHolder struct {
+	holder    uuid.UUID
+	expiresIn time.Time
+}
+
+type memoryLease struct {
+	mutex *sync.Mutex
+
+	leases map[string]*memoryLeaseHolder
+}
+
+// NewMemoryLease creating Lease for storages of one process
+func NewMemoryLease() Lease {
+	return &memoryLease{mutex: &sync.Mutex{}, leases: map[string]*memoryLeaseHolder{}}
+}
+
+func (lease *memoryLease) Acquire(_ context.Context, name string, holder uuid.UUID, ttl time.Duration) (bool, error) {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+
+	now := time.Now().In(time.UTC)
+
+	if current, exist := lease.leases[name]; exist && current.holder != holder && current.expiresIn.After(now) {
+		return false, nil
+	}
+
+	lease.leases[name] = &memoryLeaseHolder{holder: holder, expiresIn: now.Add(ttl)}
+
+	return true, nil
+}
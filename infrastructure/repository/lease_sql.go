@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+const (
+	sqlLeaseTableName = "leases"
+)
+
+type sqlLease struct {
+	db     goqu.SQLDatabase
+	tracer trace.Tracer
+}
+
+// NewSqlLease creating Lease on table of leases in the same database as tokens
+func NewSqlLease(db goqu.SQLDatabase, tracer trace.Tracer) Lease {
+	return &sqlLease{db: db, tracer: tracer}
+}
+
+func (lease *sqlLease) Acquire(ctx context.Context, name string, holder uuid.UUID, ttl time.Duration) (bool, error) {
+	ctx, span := lease.tracer.Start(ctx, "lease.acquire")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("name", name),
+		attribute.String("holder", holder.String()),
+		attribute.String("repository", "sql"),
+	)
+
+	now := time.Now().In(time.UTC)
+
+	sql, args, err := goqu.Update(sqlLeaseTableName).
+		Set(goqu.Record{"holder": holder.String(), "expires_in": now.Add(ttl)}).
+		Where(
+			goqu.I("name").Eq(name),
+			goqu.Or(goqu.I("holder").Eq(holder.String()), goqu.I("expires_in").Lte(now)),
+		).
+		ToSQL()
+	if err != nil {
+		return false, err
+	}
+
+	result, err := lease.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return false, err
+	}
+
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err == nil, err
+	}
+
+	sql, args, err = goqu.Insert(sqlLeaseTableName).
+		Rows(goqu.Record{"name": name, "holder": holder.String(), "expires_in": now.Add(ttl)}).
+		ToSQL()
+	if err != nil {
+		return false, err
+	}
+
+	if _, insertErr := lease.db.ExecContext(ctx, sql, args...); insertErr != nil {
+		// the insert fails on existing lease of other holder, any other failure is returned
+		sql, args, err := goqu.From(sqlLeaseTableName).Select("holder").Where(goqu.I("name").Eq(name)).ToSQL()
+		if err != nil {
+			return false, err
+		}
+
+		rows, err := lease.db.QueryContext(ctx, sql, args...)
+		if err != nil {
+			return false, err
+		}
+
+		defer rows.Close()
+
+		if rows.Next() {
+			return false, nil
+		}
+
+		return false, insertErr
+	}
+
+	return true, nil
+}
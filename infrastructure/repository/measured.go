@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+type measured struct {
+	repository Repository
+	duration   *prometheus.HistogramVec
+}
+
+// NewMeasured creating Repository which observes duration of every call of repository by operation
+func NewMeasured(repository Repository, duration *prometheus.HistogramVec) Repository {
+	return &measured{repository: repository, duration: duration}
+}
+
+func (repository *measured) FindByLogin(ctx context.Context, login uuid.UUID) ([]*RefreshToken, error) {
+	defer repository.observe("find_by_login", time.Now())
+
+	return repository.repository.FindByLogin(ctx, login)
+}
+
+func (repository *measured) FindByUUID(ctx context.Context, uuid uuid.UUID) (*RefreshToken, error) {
+	defer repository.observe("find_by_uuid", time.Now())
+
+	return repository.repository.FindByUUID(ctx, uuid)
+}
+
+func (repository *measured) FindRevokedByUUID(ctx context.Context, uuid uuid.UUID) (*RefreshToken, error) {
+	defer repository.observe("find_revoked_by_uuid", time.Now())
+
+	return repository.repository.FindRevokedByUUID(ctx, uuid)
+}
+
+func (repository *measured) FindAllByLogin(ctx context.Context, login uuid.UUID) ([]*RefreshToken, error) {
+	defer repository.observe("find_all_by_login", time.Now())
+
+	return repository.repository.FindAllByLogin(ctx, login)
+}
+
+func (repository *measured) Insert(ctx context.Context, tokens ...*RefreshToken) error {
+	defer repository.observe("insert", time.Now())
+
+	return repository.repository.Insert(ctx, tokens...)
+}
+
+func (repository *measured) BlockByUUID(ctx context.Context, reason RevokeReason, uuids ...uuid.UUID) error {
+	defer repository.observe("block_by_uuid", time.Now())
+
+	return repository.repository.BlockByUUID(ctx, reason, uuids...)
+}
+
+func (repository *measured) BlockByDate(ctx context.Context, date time.Time) error {
+	defer repository.observe("block_by_date", time.Now())
+
+	return repository.repository.BlockByDate(ctx, date)
+}
+
+func (repository *measured) Purge(ctx context.Context, date time.Time) error {
+	defer repository.observe("purge", time.Now())
+
+	return repository.repository.Purge(ctx, date)
+}
+
+func (repository *measured) observe(operation string, start time.Time) {
+	repository.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	MemoryDriver = "memory"
+)
+
+type memory struct {
+	// memoryOutbox outbox of events attached to writes of tokens
+	*memoryOutbox
+
+	rwMutex *sync.RWMutex
+
+	tokens  []*RefreshToken
+	archive []*ArchivedToken
+	tracer  trace.Tracer
+}
+
+// NewMemory creating in-memory Repository, data, archive and outbox are lost on restart,
+// the repository is Outbox for events attached to its writes
+func NewMemory(tracer trace.Tracer) Repository {
+	return &memory{
+		memoryOutbox: NewMemoryOutbox().(*memoryOutbox),
+		rwMutex:      &sync.RWMutex{},
+		tracer:       tracer,
+	}
+}
+
+func (repository *memory) FindByLogin(ctx context.Context, login uuid.UUID) ([]*RefreshToken, error) {
+	_, span := repository.tracer.Start(ctx, "finder.login")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("login", login.String()),
+		attribute.String("repository", MemoryDriver),
+	)
+
+	repository.rwMutex.RLock()
+	defer repository.rwMutex.RUnlock()
+
+	var refreshTokens []*RefreshToken
+
+	for _, token := range repository.tokens {
+		if token.Login == login && token.RevokedAt == nil {
+			refreshToken := *token
+			refreshTokens = append(refreshTokens, &refreshToken)
+		}
+	}
+
+	if len(refreshTokens) == 0 {
+		return nil, db.RecordNotFoundError
+	}
+
+	sort.SliceStable(refreshTokens, func(i, j int) bool {
+		return refreshTokens[i].CreatedAt.Before(refreshTokens[j].CreatedAt)
+	})
+
+	return refreshTokens, nil
+}
+
+func (repository *memory) FindByUUID(ctx context.Context, uuid uuid.UUID) (*RefreshToken, error) {
+	_, span := repository.tracer.Start(ctx, "finder.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", MemoryDriver),
+	)
+
+	repository.rwMutex.RLock()
+	defer repository.rwMutex.RUnlock()
+
+	for _, token := range repository.tokens {
+		if token.UUID == uuid && token.RevokedAt == nil {
+			refreshToken := *token
+
+			return &refreshToken, nil
+		}
+	}
+
+	return nil, db.RecordNotFoundError
+}
+
+func (repository *memory) FindRevokedByUUID(ctx context.Context, uuid uuid.UUID) (*RefreshToken, error) {
+	_, span := repository.tracer.Start(ctx, "finder.revoked.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", MemoryDriver),
+	)
+
+	repository.rwMutex.RLock()
+	defer repository.rwMutex.RUnlock()
+
+	for _, token := range repository.tokens {
+		if token.UUID == uuid && token.RevokedAt != nil {
+			refreshToken := *token
+
+			return &refreshToken, nil
+		}
+	}
+
+	for _, token := range repository.archive {
+		if token.UUID == uuid {
+			refreshToken := token.RefreshToken
+
+			return &refreshToken, nil
+		}
+	}
+
+	return nil, db.RecordNotFoundError
+}
+
+func (repository *memory) FindAllByLogin(ctx context.Context, login uuid.UUID) ([]*RefreshToken, error) {
+	_, span := repository.tracer.Start(ctx, "finder.all.login")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("login", login.String()),
+		attribute.String("repository", MemoryDriver),
+	)
+
+	repository.rwMutex.RLock()
+	defer repository.rwMutex.RUnlock()
+
+	var refreshTokens []*RefreshToken
+
+	for _, token := range repository.tokens {
+		if token.Login == login {
+			refreshToken := *token
+			refreshTokens = append(refreshTokens, &refreshToken)
+		}
+	}
+
+	for _, token := range repository.archive {
+		if token.Login == login {
+			refreshToken := token.RefreshToken
+			refreshTokens = append(refreshTokens, &refreshToken)
+		}
+	}
+
+	if len(refreshTokens) == 0 {
+		return nil, db.RecordNotFoundError
+	}
+
+	sort.SliceStable(refreshTokens, func(i, j int) bool {
+		return refreshTokens[i].CreatedAt.Before(refreshTokens[j].CreatedAt)
+	})
+
+	return refreshTokens, nil
+}
+
+func (repository *memory) Insert(ctx context.Context, tokens ...*RefreshToken) error {
+	_, span := repository.tracer.Start(ctx, "saver.insert")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("length", len(tokens)),
+		attribute.String("repository", MemoryDriver),
+	)
+
+	repository.rwMutex.Lock()
+	defer repository.rwMutex.Unlock()
+
+	exists := make(map[uuid.UUID]bool, len(repository.tokens)+len(tokens))
+	for _, token := range repository.tokens {
+		exists[token.UUID] = true
+	}
+
+	for _, token := range tokens {
+		if exists[token.UUID] {
+			return errors.New(fmt.Sprintf("memory: token '%s' already exists", token.UUID.String()))
+		}
+
+		exists[token.UUID] = true
+	}
+
+	if err := repository.Push(ctx, EventsFromContext(ctx)...); err != nil {
+		return err
+	}
+
+	now := time.Now().In(time.UTC)
+
+	for _, token := range tokens {
+		token.CreatedAt = now
+
+		refreshToken := *token
+		repository.tokens = append(repository.tokens, &refreshToken)
+	}
+
+	return nil
+}
+
+func (repository *memory) BlockByUUID(ctx context.Context, reason RevokeReason, uuids ...uuid.UUID) error {
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	_, span := repository.tracer.Start(ctx, "blocker.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("length", len(uuids)),
+		attribute.String("reason", string(reason)),
+		attribute.String("repository", MemoryDriver),
+	)
+
+	blocked := make(map[uuid.UUID]bool, len(uuids))
+	for _, uuid := range uuids {
+		blocked[uuid] = true
+	}
+
+	now := time.Now().In(time.UTC)
+
+	repository.rwMutex.Lock()
+	defer repository.rwMutex.Unlock()
+
+	if err := repository.Push(ctx, EventsFromContext(ctx)...); err != nil {
+		return err
+	}
+
+	for _, token := range repository.tokens {
+		if blocked[token.UUID] && token.RevokedAt == nil {
+			token.RevokedAt, token.RevokeReason = &now, reason
+		}
+	}
+
+	return nil
+}
+
+func (repository *memory) BlockByDate(ctx context.Context, date time.Time) error {
+	_, span := repository.tracer.Start(ctx, "blocker.date")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("repository", MemoryDriver))
+
+	repository.rwMutex.Lock()
+	defer repository.rwMutex.Unlock()
+
+	repository.tokens = repository.filter(func(token *RefreshToken) bool {
+		if token.RevokedAt == nil && !token.ExpiresIn.After(date) {
+			expiresIn := token.ExpiresIn
+			token.RevokedAt, token.RevokeReason = &expiresIn, RevokeReasonExpired
+		}
+
+		if token.RevokedAt == nil || token.RevokedAt.After(date) {
+			return true
+		}
+
+		repository.archive = append(repository.archive, &ArchivedToken{RefreshToken: *token, ArchivedAt: date})
+
+		return false
+	})
+
+	return nil
+}
+
+func (repository *memory) Purge(ctx context.Context, date time.Time) error {
+	_, span := repository.tracer.Start(ctx, "archiver.purge")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("repository", MemoryDriver))
+
+	repository.rwMutex.Lock()
+	defer repository.rwMutex.Unlock()
+
+	archive := make([]*ArchivedToken, 0, len(repository.archive))
+	for _, token := range repository.archive {
+		if token.ArchivedAt.After(date) {
+			archive = append(archive, token)
+		}
+	}
+
+	repository.archive = archive
+
+	return nil
+}
+
+// filter returns the tokens for which keep is true, must be called under the write lock
+func (repository *memory) filter(keep func(token *RefreshToken) bool) []*RefreshToken {
+	tokens := make([]*RefreshToken, 0, len(repository.tokens))
+
+	for _, token := range repository.tokens {
+		if keep(token) {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
@@ -5,12 +5,38 @@ import (
 	"time"
 )
 
+// RevokeReason cause of revocation of token
+type RevokeReason string
+
+const (
+	// RevokeReasonExpired lifetime of token is over
+	RevokeReasonExpired RevokeReason = "expired"
+	// RevokeReasonRotated token is replaced by new token on refresh
+	RevokeReasonRotated RevokeReason = "rotated"
+	// RevokeReasonLogout token is disabled by its owner
+	RevokeReasonLogout RevokeReason = "logout"
+	// RevokeReasonViolation refresh is made with ip, fingerprint or user agent which differ from token
+	RevokeReasonViolation RevokeReason = "violation"
+	// RevokeReasonLimit the oldest token is revoked on create when login has maximum of tokens
+	RevokeReasonLimit RevokeReason = "limit"
+	// RevokeReasonAdmin token is revoked by administrator
+	RevokeReasonAdmin RevokeReason = "admin"
+)
+
 type RefreshToken struct {
-	UUID        uuid.UUID `db:"uuid"`
-	Login       uuid.UUID `db:"login"`
-	Ip          string    `db:"ip"`
-	Fingerprint string    `db:"fingerprint"`
-	UserAgent   string    `db:"user_agent"`
-	CreatedAt   time.Time `db:"created_at"`
-	ExpiresIn   time.Time `db:"expires_in"`
+	UUID         uuid.UUID    `db:"uuid"`
+	Login        uuid.UUID    `db:"login"`
+	Ip           string       `db:"ip"`
+	Fingerprint  string       `db:"fingerprint"`
+	UserAgent    string       `db:"user_agent"`
+	CreatedAt    time.Time    `db:"created_at"`
+	ExpiresIn    time.Time    `db:"expires_in"`
+	RevokedAt    *time.Time   `db:"revoked_at"`
+	RevokeReason RevokeReason `db:"revoke_reason"`
+}
+
+// ArchivedToken revoked or expired token moved from active tokens, it's kept for retention period
+type ArchivedToken struct {
+	RefreshToken
+	ArchivedAt time.Time `db:"archived_at"`
 }
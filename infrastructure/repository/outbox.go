@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event record of outbox, every destination of event gets own record
+type Event struct {
+	UUID        uuid.UUID `db:"uuid"`
+	Destination string    `db:"destination"`
+	Type        string    `db:"type"`
+	Payload     []byte    `db:"payload"`
+	Attempts    uint      `db:"attempts"`
+	CreatedAt   time.Time `db:"created_at"`
+	SendAt      time.Time `db:"send_at"`
+}
+
+// Outbox events waiting for delivery, they survive restarts together with storage
+type Outbox interface {
+	// Push adding events to outbox
+	Push(ctx context.Context, events ...*Event) error
+	// Fetch return events which send_at is before or at date, ordered by send_at
+	Fetch(ctx context.Context, date time.Time, limit uint) ([]*Event, error)
+	// Retry increasing attempts of event and postponing it until date
+	Retry(ctx context.Context, uuid uuid.UUID, date time.Time) error
+	// Remove removing delivered or dropped events
+	Remove(ctx context.Context, uuids ...uuid.UUID) error
+}
+
+type eventsContextKey struct{}
+
+// WithEvents attaching events to ctx, Insert and BlockByUUID of repository write them to outbox
+// in the same transaction as tokens
+func WithEvents(ctx context.Context, events ...*Event) context.Context {
+	if len(events) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, eventsContextKey{}, append(EventsFromContext(ctx), events...))
+}
+
+// EventsFromContext return events attached to ctx
+func EventsFromContext(ctx context.Context) []*Event {
+	events, _ := ctx.Value(eventsContextKey{}).([]*Event)
+
+	return events
+}
+
+type memoryOutbox struct {
+	mutex *sync.Mutex
+
+	events map[uuid.UUID]*Event
+}
+
+// NewMemoryOutbox creating Outbox in memory, events are lost on restart
+func NewMemoryOutbox() Outbox {
+	return &memoryOutbox{mutex: &sync.Mutex{}, events: map[uuid.UUID]*Event{}}
+}
+
+func (outbox *memoryOutbox) Push(_ context.Context, events ...*Event) error {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	for _, event := range events {
+		if _, exist := outbox.events[event.UUID]; exist {
+			return errors.New(fmt.Sprintf("memory: event '%s' already exists", event.UUID.String()))
+		}
+	}
+
+	for _, event := range events {
+		outboxEvent := *event
+		outbox.events[event.UUID] = &outboxEvent
+	}
+
+	return nil
+}
+
+func (outbox *memoryOutbox) Fetch(_ context.Context, date time.Time, limit uint) ([]*Event, error) {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	var events []*Event
+
+	for _, event := range outbox.events {
+		if !event.SendAt.After(date) {
+			outboxEvent := *event
+			events = append(events, &outboxEvent)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].SendAt.Before(events[j].SendAt) })
+
+	if limit > 0 && len(events) > int(limit) {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+func (outbox *memoryOutbox) Retry(_ context.Context, uuid uuid.UUID, date time.Time) error {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	if event, exist := outbox.events[uuid]; exist {
+		event.Attempts++
+		event.SendAt = date
+	}
+
+	return nil
+}
+
+func (outbox *memoryOutbox) Remove(_ context.Context, uuids ...uuid.UUID) error {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	for _, uuid := range uuids {
+		delete(outbox.events, uuid)
+	}
+
+	return nil
+}
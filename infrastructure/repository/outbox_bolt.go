@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.etcd.io/bbolt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+const (
+	boltOutboxBucketName       = "events_outbox"
+	boltOutboxSendAtBucketName = "events_outbox_send_at"
+)
+
+type boltOutbox struct {
+	db     *bbolt.DB
+	tracer trace.Tracer
+}
+
+// NewBoltOutbox creating Outbox in the same embedded storage as tokens, events are stored by uuid
+// with secondary index 'send_at + uuid'
+func NewBoltOutbox(db *bbolt.DB, tracer trace.Tracer) (Outbox, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range []string{boltOutboxBucketName, boltOutboxSendAtBucketName} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltOutbox{db: db, tracer: tracer}, nil
+}
+
+func (outbox *boltOutbox) Push(ctx context.Context, events ...*Event) error {
+	_, span := outbox.tracer.Start(ctx, "outbox.push")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("length", len(events)),
+		attribute.String("repository", BoltDriver),
+	)
+
+	return outbox.db.Update(func(tx *bbolt.Tx) error {
+		return boltOutboxPush(tx, events...)
+	})
+}
+
+func (outbox *boltOutbox) Fetch(ctx context.Context, date time.Time, limit uint) ([]*Event, error) {
+	_, span := outbox.tracer.Start(ctx, "outbox.fetch")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("repository", BoltDriver))
+
+	until := boltTime(date)
+
+	var events []*Event
+
+	err := outbox.db.View(func(tx *bbolt.Tx) error {
+		eventsBucket := tx.Bucket([]byte(boltOutboxBucketName))
+		cursor := tx.Bucket([]byte(boltOutboxSendAtBucketName)).Cursor()
+
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key[:boltTimeLength], until) <= 0; key, _ = cursor.Next() {
+			if limit > 0 && len(events) >= int(limit) {
+				break
+			}
+
+			event, err := boltOutboxDecode(eventsBucket.Get(key[boltTimeLength:]))
+			if err != nil {
+				return err
+			}
+
+			events = append(events, event)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (outbox *boltOutbox) Retry(ctx context.Context, uuid uuid.UUID, date time.Time) error {
+	_, span := outbox.tracer.Start(ctx, "outbox.retry")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", BoltDriver),
+	)
+
+	return outbox.db.Update(func(tx *bbolt.Tx) error {
+		value := tx.Bucket([]byte(boltOutboxBucketName)).Get(uuid[:])
+		if value == nil {
+			return nil
+		}
+
+		event, err := boltOutboxDecode(value)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Bucket([]byte(boltOutboxSendAtBucketName)).Delete(boltOutboxSendAtKey(event)); err != nil {
+			return err
+		}
+
+		event.Attempts++
+		event.SendAt = date
+
+		return boltOutboxPut(tx, event)
+	})
+}
+
+func (outbox *boltOutbox) Remove(ctx context.Context, uuids ...uuid.UUID) error {
+	_, span := outbox.tracer.Start(ctx, "outbox.remove")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("length", len(uuids)),
+		attribute.String("repository", BoltDriver),
+	)
+
+	return outbox.db.Update(func(tx *bbolt.Tx) error {
+		eventsBucket := tx.Bucket([]byte(boltOutboxBucketName))
+
+		for _, uuid := range uuids {
+			value := eventsBucket.Get(uuid[:])
+			if value == nil {
+				continue
+			}
+
+			event, err := boltOutboxDecode(value)
+			if err != nil {
+				return err
+			}
+
+			if err := eventsBucket.Delete(uuid[:]); err != nil {
+				return err
+			}
+
+			if err := tx.Bucket([]byte(boltOutboxSendAtBucketName)).Delete(boltOutboxSendAtKey(event)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// boltOutboxPush adding new events, it's used by outbox and by writes of tokens with events
+func boltOutboxPush(tx *bbolt.Tx, events ...*Event) error {
+	for _, event := range events {
+		if tx.Bucket([]byte(boltOutboxBucketName)).Get(event.UUID[:]) != nil {
+			return errors.New(fmt.Sprintf("bolt: event '%s' already exists", event.UUID.String()))
+		}
+
+		if err := boltOutboxPut(tx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// boltOutboxPut writing event with index entry
+func boltOutboxPut(tx *bbolt.Tx, event *Event) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Bucket([]byte(boltOutboxBucketName)).Put(event.UUID[:], value); err != nil {
+		return err
+	}
+
+	return tx.Bucket([]byte(boltOutboxSendAtBucketName)).Put(boltOutboxSendAtKey(event), nil)
+}
+
+func boltOutboxDecode(value []byte) (*Event, error) {
+	if value == nil {
+		return nil, errors.New("bolt: index refers to missing event")
+	}
+
+	event := &Event{}
+
+	return event, json.Unmarshal(value, event)
+}
+
+// boltOutboxSendAtKey 'send_at + uuid', sorted by send_at
+func boltOutboxSendAtKey(event *Event) []byte {
+	key := make([]byte, 0, boltTimeLength+len(event.UUID))
+	key = append(key, boltTime(event.SendAt)...)
+
+	return append(key, event.UUID[:]...)
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+const (
+	sqlOutboxTableName = "events_outbox"
+)
+
+type sqlOutbox struct {
+	db     goqu.SQLDatabase
+	tracer trace.Tracer
+}
+
+// NewSqlOutbox creating Outbox on table of events in the same database as tokens
+func NewSqlOutbox(db goqu.SQLDatabase, tracer trace.Tracer) Outbox {
+	return &sqlOutbox{db: db, tracer: tracer}
+}
+
+func (outbox *sqlOutbox) Push(ctx context.Context, events ...*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	ctx, span := outbox.tracer.Start(ctx, "outbox.push")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("length", len(events)),
+		attribute.String("repository", "sql"),
+	)
+
+	sql, err := sqlOutboxInsert(events...)
+	if err != nil {
+		return err
+	}
+
+	_, err = outbox.db.ExecContext(ctx, sql)
+
+	return err
+}
+
+func (outbox *sqlOutbox) Fetch(ctx context.Context, date time.Time, limit uint) ([]*Event, error) {
+	ctx, span := outbox.tracer.Start(ctx, "outbox.fetch")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("repository", "sql"))
+
+	query := goqu.From(sqlOutboxTableName).
+		Select("uuid", "destination", "type", "payload", "attempts", "created_at", "send_at").
+		Where(goqu.I("send_at").Lte(date)).
+		Order(goqu.I("send_at").Asc())
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	sql, args, err := query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := outbox.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var events []*Event
+
+	for rows.Next() {
+		event := &Event{}
+
+		if err := rows.Scan(&event.UUID, &event.Destination, &event.Type, &event.Payload, &event.Attempts, &event.CreatedAt, &event.SendAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (outbox *sqlOutbox) Retry(ctx context.Context, uuid uuid.UUID, date time.Time) error {
+	ctx, span := outbox.tracer.Start(ctx, "outbox.retry")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", "sql"),
+	)
+
+	sql, args, err := goqu.Update(sqlOutboxTableName).
+		Set(goqu.Record{"attempts": goqu.L("attempts + 1"), "send_at": date}).
+		Where(goqu.I("uuid").Eq(uuid.String())).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = outbox.db.ExecContext(ctx, sql, args...)
+
+	return err
+}
+
+func (outbox *sqlOutbox) Remove(ctx context.Context, uuids ...uuid.UUID) error {
+	if len(uuids) == 0 {
+		return nil
+	}
+
+	ctx, span := outbox.tracer.Start(ctx, "outbox.remove")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.Int("length", len(uuids)),
+		attribute.String("repository", "sql"),
+	)
+
+	sql, args, err := goqu.Delete(sqlOutboxTableName).Where(goqu.Ex{"uuid": uuids}).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = outbox.db.ExecContext(ctx, sql, args...)
+
+	return err
+}
+
+func sqlOutboxInsert(events ...*Event) (string, error) {
+	rows := make([]interface{}, len(events))
+	for index, event := range events {
+		rows[index] = event
+	}
+
+	sql, _, err := goqu.Insert(sqlOutboxTableName).Rows(rows...).ToSQL()
+
+	return sql, err
+}
@@ -9,19 +9,32 @@ import (
 type Finder interface {
 	FindByLogin(ctx context.Context, login uuid.UUID) ([]*RefreshToken, error)
 	FindByUUID(ctx context.Context, uuid uuid.UUID) (*RefreshToken, error)
+	// FindRevokedByUUID finding revoked token, including archived
+	FindRevokedByUUID(ctx context.Context, uuid uuid.UUID) (*RefreshToken, error)
+	// FindAllByLogin finding tokens of login including revoked and archived, ordered by created_at
+	FindAllByLogin(ctx context.Context, login uuid.UUID) ([]*RefreshToken, error)
 }
 
 type Saver interface {
 	Insert(ctx context.Context, tokens ...*RefreshToken) error
 }
 
+// Blocker revoking tokens, revoked tokens are not found by Finder
 type Blocker interface {
-	BlockByUUID(ctx context.Context, uuids ...uuid.UUID) error
+	// BlockByUUID marking tokens as revoked by reason
+	BlockByUUID(ctx context.Context, reason RevokeReason, uuids ...uuid.UUID) error
+	// BlockByDate marking tokens expired until date as revoked and moving revoked tokens to archive
 	BlockByDate(ctx context.Context, date time.Time) error
 }
 
+type Archiver interface {
+	// Purge removing tokens archived until date
+	Purge(ctx context.Context, date time.Time) error
+}
+
 type Repository interface {
 	Finder
 	Saver
 	Blocker
+	Archiver
 }
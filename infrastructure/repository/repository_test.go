@@ -0,0 +1,118 @@
+package repository_test
+
+import (
+	"context"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/Diez37/go-skeleton/infrastructure/repository/conformance"
+	"github.com/diez37/go-packages/clients/db"
+	"github.com/diez37/go-packages/clients/db/sqlite"
+	"github.com/diez37/go-packages/migrator"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.etcd.io/bbolt"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSql(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) repository.Repository {
+		logger := logrus.New()
+		logger.SetOutput(io.Discard)
+
+		dbConfig := &db.Config{Driver: db.SQLiteDriver}
+
+		sqlDatabase, err := sqlite.NewSQLite(&sqlite.Config{Dsn: filepath.Join(t.TempDir(), "db")}, logger)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		migrate, err := migrator.NewMigrator(&migrator.Config{Source: "file://../../migrations"}, dbConfig, sqlDatabase)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := migrate.Up(); err != nil {
+			t.Fatal(err)
+		}
+
+		return repository.NewSql(sqlDatabase, trace.NewNoopTracerProvider().Tracer(""))
+	})
+}
+
+func TestMemory(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) repository.Repository {
+		return repository.NewMemory(trace.NewNoopTracerProvider().Tracer(""))
+	})
+}
+
+func TestBolt(t *testing.T) {
+	conformance.Run(t, func(t *testing.T) repository.Repository {
+		db, err := bbolt.Open(filepath.Join(t.TempDir(), "db"), 0600, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() { _ = db.Close() })
+
+		tokenRepository, err := repository.NewBolt(db, trace.NewNoopTracerProvider().Tracer(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tokenRepository
+	})
+}
+
+func TestSqlMigrationsDown(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	sqlDatabase, err := sqlite.NewSQLite(&sqlite.Config{Dsn: filepath.Join(t.TempDir(), "db")}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrate, err := migrator.NewMigrator(&migrator.Config{Source: "file://../../migrations"}, &db.Config{Driver: db.SQLiteDriver}, sqlDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrate.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	tokenRepository := repository.NewSql(sqlDatabase, trace.NewNoopTracerProvider().Tracer(""))
+
+	now := time.Now().In(time.UTC).Truncate(time.Second)
+	active := &repository.RefreshToken{UUID: uuid.New(), Login: uuid.New(), CreatedAt: now, ExpiresIn: now.Add(time.Hour)}
+	revoked := &repository.RefreshToken{UUID: uuid.New(), Login: active.Login, CreatedAt: now, ExpiresIn: now.Add(time.Hour)}
+
+	if err := tokenRepository.Insert(context.Background(), active, revoked); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tokenRepository.BlockByUUID(context.Background(), repository.RevokeReasonLogout, revoked.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	// every migration is rolled back and applied again
+	if err := migrate.Steps(-2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrate.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tokenRepository.FindByUUID(context.Background(), active.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the revoked tokens were deleted before archive
+	if _, err := tokenRepository.FindRevokedByUUID(context.Background(), revoked.UUID); err != db.RecordNotFoundError {
+		t.Fatalf("revoked token survived rollback of archive, error - %v", err)
+	}
+}
@@ -7,11 +7,19 @@ import (
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
+	"sort"
 	"time"
 )
 
 const (
-	sqlTableName = "refresh_tokens"
+	sqlTableName        = "refresh_tokens"
+	sqlArchiveTableName = "refresh_tokens_archive"
+)
+
+var (
+	sqlColumns        = []interface{}{"uuid", "login", "ip", "fingerprint", "user_agent", "created_at", "expires_in"}
+	sqlArchiveColumns = []interface{}{"uuid", "login", "ip", "fingerprint", "user_agent", "created_at", "expires_in", "revoked_at", "revoke_reason"}
 )
 
 type sql struct {
@@ -33,7 +41,8 @@ func (repository *sql) FindByLogin(ctx context.Context, login uuid.UUID) ([]*Ref
 	)
 
 	sql, args, err := goqu.From(sqlTableName).
-		Where(goqu.I("login").Eq(login.String())).
+		Select(sqlColumns...).
+		Where(goqu.I("login").Eq(login.String()), goqu.I("revoked_at").IsNull()).
 		Order(goqu.I("created_at").Asc()).
 		ToSQL()
 	if err != nil {
@@ -75,7 +84,10 @@ func (repository *sql) FindByUUID(ctx context.Context, uuid uuid.UUID) (*Refresh
 		attribute.String("repository", "sql"),
 	)
 
-	sql, args, err := goqu.From(sqlTableName).Where(goqu.I("uuid").Eq(uuid.String())).ToSQL()
+	sql, args, err := goqu.From(sqlTableName).
+		Select(sqlColumns...).
+		Where(goqu.I("uuid").Eq(uuid.String()), goqu.I("revoked_at").IsNull()).
+		ToSQL()
 	if err != nil {
 		return nil, err
 	}
@@ -85,6 +97,8 @@ func (repository *sql) FindByUUID(ctx context.Context, uuid uuid.UUID) (*Refresh
 		return nil, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 		refreshToken := &RefreshToken{}
 
@@ -98,6 +112,120 @@ func (repository *sql) FindByUUID(ctx context.Context, uuid uuid.UUID) (*Refresh
 	return nil, db.RecordNotFoundError
 }
 
+func (repository *sql) FindRevokedByUUID(ctx context.Context, uuid uuid.UUID) (*RefreshToken, error) {
+	ctx, span := repository.tracer.Start(ctx, "finder.revoked.uuid")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("uuid", uuid.String()),
+		attribute.String("repository", "sql"),
+	)
+
+	// revoked token is in table of tokens until sweep, after sweep it's in archive
+	queries := []*goqu.SelectDataset{
+		goqu.From(sqlTableName).Where(goqu.I("uuid").Eq(uuid.String()), goqu.I("revoked_at").IsNotNull()),
+		goqu.From(sqlArchiveTableName).Where(goqu.I("uuid").Eq(uuid.String())),
+	}
+
+	for _, query := range queries {
+		refreshToken, err := repository.findRevoked(ctx, query.Select(sqlArchiveColumns...))
+		if err != db.RecordNotFoundError {
+			return refreshToken, err
+		}
+	}
+
+	return nil, db.RecordNotFoundError
+}
+
+func (repository *sql) FindAllByLogin(ctx context.Context, login uuid.UUID) ([]*RefreshToken, error) {
+	ctx, span := repository.tracer.Start(ctx, "finder.all.login")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("login", login.String()),
+		attribute.String("repository", "sql"),
+	)
+
+	queries := []*goqu.SelectDataset{
+		goqu.From(sqlTableName).Where(goqu.I("login").Eq(login.String())),
+		goqu.From(sqlArchiveTableName).Where(goqu.I("login").Eq(login.String())),
+	}
+
+	var refreshTokens []*RefreshToken
+
+	for _, query := range queries {
+		tokens, err := repository.findAll(ctx, query.Select(sqlArchiveColumns...))
+		if err != nil {
+			return nil, err
+		}
+
+		refreshTokens = append(refreshTokens, tokens...)
+	}
+
+	if len(refreshTokens) == 0 {
+		return nil, db.RecordNotFoundError
+	}
+
+	sort.SliceStable(refreshTokens, func(i, j int) bool {
+		return refreshTokens[i].CreatedAt.Before(refreshTokens[j].CreatedAt)
+	})
+
+	return refreshTokens, nil
+}
+
+func (repository *sql) findRevoked(ctx context.Context, query *goqu.SelectDataset) (*RefreshToken, error) {
+	tokens, err := repository.findAll(ctx, query.Limit(1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, db.RecordNotFoundError
+	}
+
+	return tokens[0], nil
+}
+
+// findAll return tokens of query which selects columns of archive
+func (repository *sql) findAll(ctx context.Context, query *goqu.SelectDataset) ([]*RefreshToken, error) {
+	sql, args, err := query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repository.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var refreshTokens []*RefreshToken
+
+	for rows.Next() {
+		refreshToken := &RefreshToken{}
+
+		err := rows.Scan(
+			&refreshToken.UUID,
+			&refreshToken.Login,
+			&refreshToken.Ip,
+			&refreshToken.Fingerprint,
+			&refreshToken.UserAgent,
+			&refreshToken.CreatedAt,
+			&refreshToken.ExpiresIn,
+			&refreshToken.RevokedAt,
+			&refreshToken.RevokeReason,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		refreshTokens = append(refreshTokens, refreshToken)
+	}
+
+	return refreshTokens, rows.Err()
+}
+
 func (repository *sql) Insert(ctx context.Context, tokens ...*RefreshToken) error {
 	ctx, span := repository.tracer.Start(ctx, "saver.insert")
 	defer span.End()
@@ -116,17 +244,15 @@ func (repository *sql) Insert(ctx context.Context, tokens ...*RefreshToken) erro
 		rows[index] = token
 	}
 
-	sql, args, err := goqu.Insert(sqlTableName).Rows(rows...).ToSQL()
+	sql, _, err := goqu.Insert(sqlTableName).Rows(rows...).ToSQL()
 	if err != nil {
 		return err
 	}
 
-	_, err = repository.db.ExecContext(ctx, sql, args...)
-
-	return err
+	return repository.exec(ctx, sql)
 }
 
-func (repository *sql) BlockByUUID(ctx context.Context, uuids ...uuid.UUID) error {
+func (repository *sql) BlockByUUID(ctx context.Context, reason RevokeReason, uuids ...uuid.UUID) error {
 	if len(uuids) == 0 {
 		return nil
 	}
@@ -136,17 +262,19 @@ func (repository *sql) BlockByUUID(ctx context.Context, uuids ...uuid.UUID) erro
 
 	span.SetAttributes(
 		attribute.Int("length", len(uuids)),
+		attribute.String("reason", string(reason)),
 		attribute.String("repository", "sql"),
 	)
 
-	sql, args, err := goqu.Delete(sqlTableName).Where(goqu.Ex{"uuid": uuids}).ToSQL()
+	sql, _, err := goqu.Update(sqlTableName).
+		Set(goqu.Record{"revoked_at": time.Now().In(time.UTC), "revoke_reason": reason}).
+		Where(goqu.Ex{"uuid": uuids}, goqu.I("revoked_at").IsNull()).
+		ToSQL()
 	if err != nil {
 		return err
 	}
 
-	_, err = repository.db.ExecContext(ctx, sql, args...)
-
-	return err
+	return repository.exec(ctx, sql)
 }
 
 func (repository *sql) BlockByDate(ctx context.Context, date time.Time) error {
@@ -155,7 +283,40 @@ func (repository *sql) BlockByDate(ctx context.Context, date time.Time) error {
 
 	span.SetAttributes(attribute.String("repository", "sql"))
 
-	sql, args, err := goqu.Delete(sqlTableName).Where(goqu.I("expires_in").Lte(date)).ToSQL()
+	expire, _, err := goqu.Update(sqlTableName).
+		Set(goqu.Record{"revoked_at": goqu.I("expires_in"), "revoke_reason": RevokeReasonExpired}).
+		Where(goqu.I("expires_in").Lte(date), goqu.I("revoked_at").IsNull()).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+
+	archive, _, err := goqu.Insert(sqlArchiveTableName).
+		Cols(append(sqlArchiveColumns, "archived_at")...).
+		FromQuery(goqu.From(sqlTableName).
+			Select(append(sqlArchiveColumns, goqu.V(date))...).
+			Where(goqu.I("revoked_at").Lte(date)),
+		).
+		ToSQL()
+	if err != nil {
+		return err
+	}
+
+	remove, _, err := goqu.Delete(sqlTableName).Where(goqu.I("revoked_at").Lte(date)).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	return repository.transaction(ctx, expire, archive, remove)
+}
+
+func (repository *sql) Purge(ctx context.Context, date time.Time) error {
+	ctx, span := repository.tracer.Start(ctx, "archiver.purge")
+	defer span.End()
+
+	span.SetAttributes(attribute.String("repository", "sql"))
+
+	sql, args, err := goqu.Delete(sqlArchiveTableName).Where(goqu.I("archived_at").Lte(date)).ToSQL()
 	if err != nil {
 		return err
 	}
@@ -164,3 +325,36 @@ func (repository *sql) BlockByDate(ctx context.Context, date time.Time) error {
 
 	return err
 }
+
+// exec executing statement, the events attached to ctx are written to outbox in the same transaction
+func (repository *sql) exec(ctx context.Context, statement string) error {
+	events := EventsFromContext(ctx)
+	if len(events) == 0 {
+		_, err := repository.db.ExecContext(ctx, statement)
+
+		return err
+	}
+
+	outbox, err := sqlOutboxInsert(events...)
+	if err != nil {
+		return err
+	}
+
+	return repository.transaction(ctx, statement, outbox)
+}
+
+// transaction executing statements in one transaction
+func (repository *sql) transaction(ctx context.Context, statements ...string) error {
+	tx, err := repository.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return multierr.Append(err, tx.Rollback())
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,24 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+type env struct{}
+
+// NewEnv creating Provider of environment variables, the path is name of variable, e.g. 'env://TOKENS_SECRET'
+func NewEnv() Provider {
+	return &env{}
+}
+
+func (provider *env) Read(_ context.Context, path string) ([]byte, error) {
+	value, ok := os.LookupEnv(path)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("environment variable '%s' not set", path))
+	}
+
+	return []byte(value), nil
+}
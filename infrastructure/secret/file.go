@@ -0,0 +1,24 @@
+package secret
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+type file struct{}
+
+// NewFile creating Provider of files, the path is absolute path to file, e.g. 'file:///run/secrets/token'.
+// The trailing line break is trimmed, so the file may be written by 'echo'
+func NewFile() Provider {
+	return &file{}
+}
+
+func (provider *file) Read(_ context.Context, path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.TrimRight(string(content), "\r\n")), nil
+}
@@ -0,0 +1,123 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	SchemeFile  = "file"
+	SchemeEnv   = "env"
+	SchemeVault = "vault"
+
+	// Redacted replacement of secret in output
+	Redacted = "[redacted]"
+
+	schemeSeparator = "://"
+)
+
+// Provider reading secret of reference, path is the part of reference after 'scheme://'
+type Provider interface {
+	Read(ctx context.Context, path string) ([]byte, error)
+}
+
+// Resolver resolving references of secrets by providers of their schemes
+type Resolver interface {
+	// Resolve return secret of reference, value without scheme is secret itself
+	// and the scheme without provider is an error, so reference is never used as secret
+	Resolve(ctx context.Context, value string) ([]byte, error)
+
+	// IsReference checking that value has scheme
+	IsReference(value string) bool
+}
+
+type resolver struct {
+	providers map[string]Provider
+}
+
+// NewResolver creating Resolver with providers by their schemes
+func NewResolver(providers map[string]Provider) Resolver {
+	return &resolver{providers: providers}
+}
+
+func (resolver *resolver) Resolve(ctx context.Context, value string) ([]byte, error) {
+	scheme, path := split(value)
+	if scheme == "" {
+		return []byte(value), nil
+	}
+
+	provider, ok := resolver.providers[scheme]
+	if !ok {
+		schemes := make([]string, 0, len(resolver.providers))
+		for known := range resolver.providers {
+			schemes = append(schemes, known)
+		}
+
+		sort.Strings(schemes)
+
+		return nil, errors.New(fmt.Sprintf(
+			"secret: reference '%s' has scheme '%s' without provider, availably [%s]",
+			value, scheme, strings.Join(schemes, ","),
+		))
+	}
+
+	content, err := provider.Read(ctx, path)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("secret: reference '%s' - %s", value, err))
+	}
+
+	return content, nil
+}
+
+func (resolver *resolver) IsReference(value string) bool {
+	scheme, _ := split(value)
+
+	return scheme != ""
+}
+
+// split return scheme and path of reference, value without separator has no scheme
+func split(value string) (string, string) {
+	index := strings.Index(value, schemeSeparator)
+	if index < 0 {
+		return "", value
+	}
+
+	return value[:index], value[index+len(schemeSeparator):]
+}
+
+// Redact return value for output, the references of known schemes are kept and other not empty values are replaced
+func Redact(value string) string {
+	switch scheme, _ := split(value); scheme {
+	case SchemeFile, SchemeEnv, SchemeVault:
+		return value
+	}
+
+	if value == "" {
+		return value
+	}
+
+	return Redacted
+}
+
+// RedactUrl return url or address for output, the userinfo before '@' is replaced
+func RedactUrl(value string) string {
+	start := 0
+	if index := strings.Index(value, schemeSeparator); index >= 0 {
+		start = index + len(schemeSeparator)
+	}
+
+	end := len(value)
+	if index := strings.IndexAny(value[start:], "/?#"); index >= 0 {
+		end = start + index
+	}
+
+	index := strings.LastIndex(value[start:end], "@")
+	if index < 0 {
+		return value
+	}
+
+	return value[:start] + Redacted + value[start+index:]
+}
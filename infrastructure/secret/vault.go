@@ -0,0 +1,94 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"strings"
+)
+
+const (
+	VaultTokenHeader = "X-Vault-Token"
+
+	// VaultFieldDefault field of secret of reference without field
+	VaultFieldDefault = "value"
+
+	fieldSeparator = "#"
+)
+
+// vaultResponse body of response of reading secret, KV v2 nests fields of secret into 'data.data'
+type vaultResponse struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+type vault struct {
+	client  *http.Client
+	address string
+	token   string
+	tracer  trace.Tracer
+}
+
+// NewVault creating Provider of HashiCorp Vault by HTTP API, the path is path of secret and its field,
+// e.g. 'vault://secret/data/tokenizer#secret', the both engines KV v1 and KV v2 are supported
+func NewVault(client *http.Client, address string, token string, tracer trace.Tracer) Provider {
+	return &vault{client: client, address: strings.TrimRight(address, "/"), token: token, tracer: tracer}
+}
+
+func (provider *vault) Read(ctx context.Context, path string) ([]byte, error) {
+	ctx, span := provider.tracer.Start(ctx, "secret.vault.read")
+	defer span.End()
+
+	field := VaultFieldDefault
+	if index := strings.LastIndex(path, fieldSeparator); index >= 0 {
+		path, field = path[:index], path[index+len(fieldSeparator):]
+	}
+
+	span.SetAttributes(
+		attribute.String("address", provider.address),
+		attribute.String("path", path),
+		attribute.String("field", field),
+	)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/%s", provider.address, strings.TrimLeft(path, "/")), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set(VaultTokenHeader, provider.token)
+
+	response, err := provider.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	span.SetAttributes(attribute.Int("status", response.StatusCode))
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New(fmt.Sprintf("vault responded with status %d", response.StatusCode))
+	}
+
+	body := &vaultResponse{}
+	if err := json.NewDecoder(response.Body).Decode(body); err != nil {
+		return nil, err
+	}
+
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+
+	value, ok := data[field].(string)
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("field '%s' of secret not found or not string", field))
+	}
+
+	return []byte(value), nil
+}
@@ -0,0 +1,117 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testVaultToken = "vault-token"
+)
+
+// newVaultServer serving secrets of KV v1 at 'kv/tokenizer' and of KV v2 at 'secret/data/tokenizer'
+func newVaultServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	secrets := map[string]interface{}{
+		"/v1/kv/tokenizer": map[string]interface{}{
+			"data": map[string]interface{}{"value": "v1-value", "secret": "v1-secret", "number": 1},
+		},
+		"/v1/secret/data/tokenizer": map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     map[string]interface{}{"value": "v2-value", "secret": "v2-secret"},
+				"metadata": map[string]interface{}{"version": 3},
+			},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get(VaultTokenHeader) != testVaultToken {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		body, exist := secrets[request.URL.Path]
+		if !exist {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if err := json.NewEncoder(writer).Encode(body); err != nil {
+			t.Error(err)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestVaultRead(t *testing.T) {
+	server := newVaultServer(t)
+	provider := NewVault(server.Client(), server.URL+"/", testVaultToken, trace.NewNoopTracerProvider().Tracer(""))
+
+	for path, want := range map[string]string{
+		"kv/tokenizer":                  "v1-value",
+		"kv/tokenizer#secret":           "v1-secret",
+		"secret/data/tokenizer":         "v2-value",
+		"/secret/data/tokenizer#secret": "v2-secret",
+	} {
+		value, err := provider.Read(context.Background(), path)
+		if err != nil {
+			t.Fatalf("path '%s', error - %s", path, err)
+		}
+
+		if string(value) != want {
+			t.Fatalf("path '%s', value '%s', want '%s'", path, value, want)
+		}
+	}
+}
+
+func TestVaultReadErrors(t *testing.T) {
+	server := newVaultServer(t)
+	tracer := trace.NewNoopTracerProvider().Tracer("")
+
+	for name, test := range map[string]struct {
+		token string
+		path  string
+		error string
+	}{
+		"forbidden":            {token: "other", path: "kv/tokenizer", error: "status 403"},
+		"not found":            {token: testVaultToken, path: "kv/unknown", error: "status 404"},
+		"missing field":        {token: testVaultToken, path: "secret/data/tokenizer#unknown", error: "field 'unknown'"},
+		"not string field":     {token: testVaultToken, path: "kv/tokenizer#number", error: "field 'number'"},
+		"field of v2 metadata": {token: testVaultToken, path: "secret/data/tokenizer#version", error: "field 'version'"},
+	} {
+		_, err := NewVault(server.Client(), server.URL, test.token, tracer).Read(context.Background(), test.path)
+		if err == nil || !strings.Contains(err.Error(), test.error) {
+			t.Fatalf("%s: error %v, want '%s'", name, err, test.error)
+		}
+	}
+}
+
+func TestResolveVault(t *testing.T) {
+	server := newVaultServer(t)
+	resolver := NewResolver(map[string]Provider{
+		SchemeVault: NewVault(server.Client(), server.URL, testVaultToken, trace.NewNoopTracerProvider().Tracer("")),
+	})
+
+	value, err := resolver.Resolve(context.Background(), "vault://secret/data/tokenizer#secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(value) != "v2-secret" {
+		t.Fatalf("value '%s', want 'v2-secret'", value)
+	}
+
+	// the error names reference and not secret
+	if _, err := resolver.Resolve(context.Background(), "vault://kv/unknown"); err == nil || !strings.Contains(err.Error(), "vault://kv/unknown") {
+		t.Fatalf("error %v, want error of reference", err)
+	}
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/Diez37/go-skeleton/infrastructure/publisher"
+	"github.com/go-http-utils/headers"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Tokenizer-Signature"
+	TimestampHeader = "X-Tokenizer-Timestamp"
+	EventHeader     = "X-Tokenizer-Event"
+	DeliveryHeader  = "X-Tokenizer-Delivery"
+
+	SignaturePrefix = "sha256="
+)
+
+type webhook struct {
+	client *http.Client
+	url    string
+	secret []byte
+	tracer trace.Tracer
+}
+
+// NewWebhook creating publisher.Publisher which sends payload of event by POST request to url,
+// every request is signed by HMAC-SHA256 of secret, see Sign, the response with status other than 2xx is an error
+func NewWebhook(client *http.Client, url string, secret string, tracer trace.Tracer) publisher.Publisher {
+	return &webhook{client: client, url: url, secret: []byte(secret), tracer: tracer}
+}
+
+func (webhook *webhook) Publish(ctx context.Context, delivery uuid.UUID, eventType string, payload []byte) error {
+	ctx, span := webhook.tracer.Start(ctx, "webhook.send")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("url", webhook.url),
+		attribute.String("event", eventType),
+		attribute.String("delivery", delivery.String()),
+	)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set(headers.ContentType, "application/json")
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(EventHeader, eventType)
+	request.Header.Set(DeliveryHeader, delivery.String())
+	request.Header.Set(SignatureHeader, Sign(webhook.secret, timestamp, payload))
+
+	response, err := webhook.client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	span.SetAttributes(attribute.Int("status", response.StatusCode))
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return errors.New(fmt.Sprintf("webhook: '%s' responded with status %d", webhook.url, response.StatusCode))
+	}
+
+	return nil
+}
+
+func (webhook *webhook) Close() error {
+	return nil
+}
+
+// Sign return signature of request, HMAC-SHA256 of 'timestamp.payload' in hex with prefix 'sha256=',
+// the receiver checks it with the value of header X-Tokenizer-Timestamp
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return SignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookPublish(t *testing.T) {
+	delivery := uuid.New()
+	payload := []byte(`{"login":"login"}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		if err != nil {
+			t.Error(err)
+		}
+
+		if string(body) != string(payload) {
+			t.Errorf("body '%s', want '%s'", body, payload)
+		}
+
+		if signature := Sign([]byte("secret"), request.Header.Get(TimestampHeader), body); request.Header.Get(SignatureHeader) != signature {
+			t.Errorf("signature '%s', want '%s'", request.Header.Get(SignatureHeader), signature)
+		}
+
+		if request.Header.Get(DeliveryHeader) != delivery.String() || request.Header.Get(EventHeader) != "session.created" {
+			t.Errorf("headers %v, want delivery '%s' and event 'session.created'", request.Header, delivery)
+		}
+
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	publisher := NewWebhook(server.Client(), server.URL, "secret", trace.NewNoopTracerProvider().Tracer(""))
+
+	if err := publisher.Publish(context.Background(), delivery, "session.created", payload); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebhookPublishStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	publisher := NewWebhook(server.Client(), server.URL, "secret", trace.NewNoopTracerProvider().Tracer(""))
+
+	if err := publisher.Publish(context.Background(), uuid.New(), "session.created", []byte(`{}`)); err == nil {
+		t.Fatal("publish with status 503 succeeded")
+	}
+}
+
+func TestSign(t *testing.T) {
+	signature := Sign([]byte("secret"), "1650000000", []byte(`{}`))
+
+	if signature != Sign([]byte("secret"), "1650000000", []byte(`{}`)) {
+		t.Fatal("signature is not stable")
+	}
+
+	if signature == Sign([]byte("secret"), "1650000001", []byte(`{}`)) || signature == Sign([]byte("other"), "1650000000", []byte(`{}`)) {
+		t.Fatal("signature does not depend on timestamp and secret")
+	}
+}
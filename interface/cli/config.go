@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/keyring"
+	"github.com/Diez37/go-skeleton/infrastructure/secret"
+	"github.com/diez37/go-packages/configurator"
+	"github.com/diez37/go-packages/container"
+	"github.com/spf13/cobra"
+	"go.uber.org/multierr"
+	"sort"
+	"strings"
+)
+
+// newConfigCommand creating command group for configuration merged of flags, environment and config file
+func newConfigCommand(container container.Container) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "checking configuration",
+	}
+
+	outputFlag(cmd, &output)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "check",
+		Short: "printing effective configuration with redacted secrets and validating it, invalid configuration is an error",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := checkOutput(output); err != nil {
+				return err
+			}
+
+			if err := container.Invoke(func(
+				tokenConfig *config.Token,
+				cookieConfig *config.Cookie,
+				eventsConfig *config.Events,
+				secretsConfig *config.Secrets,
+				profileConfig *config.Profile,
+				migratorConfig *config.Migrator,
+				configurator configurator.Configurator,
+			) error {
+				eventsConfig.Configure(configurator)
+				secretsConfig.Configure(configurator)
+				profileConfig.Configure(configurator)
+				migratorConfig.Configure(configurator)
+
+				settings := map[string]interface{}{}
+				for _, part := range []map[string]interface{}{
+					profileConfig.Settings(),
+					tokenConfig.Settings(),
+					cookieConfig.Settings(),
+					eventsConfig.Settings(),
+					secretsConfig.Settings(),
+					migratorConfig.Settings(),
+				} {
+					for name, value := range part {
+						settings[name] = value
+					}
+				}
+
+				for _, name := range []string{config.TokensSecretFieldName, config.EventsSecretFieldName, config.SecretsVaultTokenFieldName} {
+					settings[name] = secret.Redact(settings[name].(string))
+				}
+
+				for _, name := range []string{config.EventsNatsUrlFieldName, config.SecretsVaultAddressFieldName} {
+					settings[name] = secret.RedactUrl(settings[name].(string))
+				}
+
+				for _, name := range []string{config.EventsWebhooksFieldName, config.EventsKafkaBrokersFieldName} {
+					urls := make([]string, 0, len(settings[name].([]string)))
+					for _, value := range settings[name].([]string) {
+						urls = append(urls, secret.RedactUrl(value))
+					}
+
+					settings[name] = urls
+				}
+
+				names := make([]string, 0, len(settings))
+				for name := range settings {
+					names = append(names, name)
+				}
+
+				sort.Strings(names)
+
+				rows := make([][]string, 0, len(names))
+				for _, name := range names {
+					value := fmt.Sprint(settings[name])
+					if values, ok := settings[name].([]string); ok {
+						value = strings.Join(values, ",")
+					}
+
+					rows = append(rows, []string{name, value})
+				}
+
+				if err := writeOutput(cmd, output, settings, []string{"NAME", "VALUE"}, rows); err != nil {
+					return err
+				}
+
+				return multierr.Combine(
+					profileConfig.Validate(),
+					tokenConfig.Validate(eventsConfig),
+					cookieConfig.Validate(),
+					eventsConfig.Validate(profileConfig),
+					secretsConfig.Validate(),
+				)
+			}); err != nil {
+				return err
+			}
+
+			// the secret and keyring are resolved and checked by profile as on start
+			return container.Invoke(func(keyring.Keyring) {})
+		},
+	})
+
+	return cmd
+}
+
+// validateTokens checking configuration of tokens before creating of services
+func validateTokens(container container.Container) error {
+	return container.Invoke(func(tokenConfig *config.Token, eventsConfig *config.Events, configurator configurator.Configurator) error {
+		eventsConfig.Configure(configurator)
+
+		return tokenConfig.Validate(eventsConfig)
+	})
+}
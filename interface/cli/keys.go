@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	container2 "github.com/Diez37/go-skeleton/infrastructure/container"
+	"github.com/Diez37/go-skeleton/infrastructure/keyring"
+	"github.com/diez37/go-packages/configurator"
+	"github.com/diez37/go-packages/container"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	KeyFormatPem = "pem"
+	KeyFormatJwk = "jwk"
+)
+
+// keysCommand dependencies of subcommands of keys
+type keysCommand struct {
+	container container.Container
+	output    string
+}
+
+// newKeysCommand creating command group for keyring of signing, it operates on file of flag 'tokens.keyring' which is read by server
+func newKeysCommand(container container.Container) *cobra.Command {
+	command := &keysCommand{container: container}
+
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "generating and rotating keys of signing of access tokens",
+	}
+
+	outputFlag(cmd, &command.output)
+
+	var algorithm, format, out string
+	var activateAfter, retireAfter time.Duration
+
+	algorithms := fmt.Sprintf("algorithm of key, availably [%s]", strings.Join(keyring.Algorithms, ","))
+
+	generate := &cobra.Command{
+		Use:   "generate",
+		Short: "generating key and writing its private part as PEM or JWK, keyring is not changed",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			key, err := keyring.Generate(algorithm)
+			if err != nil {
+				return err
+			}
+
+			var content []byte
+
+			switch format {
+			case KeyFormatPem:
+				content, err = key.PEM()
+			case KeyFormatJwk:
+				content, err = marshalJwk(key.JWK(true))
+			default:
+				err = errors.New(fmt.Sprintf("keys: format '%s' unknown", format))
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if out == "" {
+				_, err = cmd.OutOrStdout().Write(content)
+
+				return err
+			}
+
+			return os.WriteFile(out, content, 0600)
+		},
+	}
+	generate.Flags().StringVar(&algorithm, "alg", keyring.AlgorithmES256, algorithms)
+	generate.Flags().StringVar(&format, "format", KeyFormatPem, fmt.Sprintf("format of key, availably [%s]", strings.Join([]string{KeyFormatPem, KeyFormatJwk}, ",")))
+	generate.Flags().StringVar(&out, "out", "", "path to file of key, empty value writes key to stdout")
+
+	rotate := &cobra.Command{
+		Use:   "rotate",
+		Short: "adding new key to keyring, it signs tokens after activation and the previous key verifies tokens until retire",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if activateAfter == 0 {
+				err := command.container.Invoke(func(secretsConfig *config.Secrets, configurator configurator.Configurator) {
+					secretsConfig.Configure(configurator)
+					activateAfter = secretsConfig.Delay
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			return command.run(func(tokenConfig *config.Token, storage keyring.Storage, keys []*keyring.Key) error {
+				key, err := keyring.Generate(algorithm)
+				if err != nil {
+					return err
+				}
+
+				if retireAfter == 0 {
+					retireAfter = tokenConfig.AccessLifetime
+				}
+
+				activateAt := time.Now().In(time.UTC).Add(activateAfter)
+
+				keys = keyring.Rotate(keys, key, activateAt, activateAt.Add(retireAfter))
+				if err := storage.Save(keys); err != nil {
+					return err
+				}
+
+				return command.list(cmd, keys)
+			})
+		},
+	}
+	rotate.Flags().StringVar(&algorithm, "alg", keyring.AlgorithmES256, algorithms)
+	rotate.Flags().DurationVar(
+		&activateAfter,
+		"activate-after",
+		0,
+		fmt.Sprintf("time while new key only verifies tokens until every replica reloads keyring, zero value uses '%s'", config.SecretsDelayFieldName),
+	)
+	rotate.Flags().DurationVar(&retireAfter, "retire-after", 0, "time while previous key verifies tokens after activation of new key, zero value uses lifetime of access token")
+
+	cmd.AddCommand(
+		generate,
+		&cobra.Command{
+			Use:   "list",
+			Short: "printing keys of keyring with their state",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				return command.run(func(_ *config.Token, _ keyring.Storage, keys []*keyring.Key) error {
+					return command.list(cmd, keys)
+				})
+			},
+		},
+		rotate,
+		&cobra.Command{
+			Use:   "export-jwks",
+			Short: "printing public keys of not retired asymmetric keys as JWKS",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				return command.run(func(_ *config.Token, _ keyring.Storage, keys []*keyring.Key) error {
+					now := time.Now().In(time.UTC)
+
+					jwks := make([]map[string]interface{}, 0, len(keys))
+					for _, key := range keys {
+						if key.IsSymmetric() || key.State(now) == keyring.StateRetired {
+							continue
+						}
+
+						jwk, err := key.JWK(false)
+						if err != nil {
+							return err
+						}
+
+						jwks = append(jwks, jwk)
+					}
+
+					content, err := marshalJwk(map[string]interface{}{"keys": jwks}, nil)
+					if err != nil {
+						return err
+					}
+
+					_, err = cmd.OutOrStdout().Write(content)
+
+					return err
+				})
+			},
+		},
+	)
+
+	return cmd
+}
+
+// run loading keys from file of keyring and running action with them
+func (command *keysCommand) run(action func(tokenConfig *config.Token, storage keyring.Storage, keys []*keyring.Key) error) error {
+	if err := checkOutput(command.output); err != nil {
+		return err
+	}
+
+	return command.container.Invoke(func(tokenConfig *config.Token) error {
+		if tokenConfig.Keyring == "" {
+			return errors.New(fmt.Sprintf("keys: path to keyring is empty, use flag '%s'", config.TokensKeyringFieldName))
+		}
+
+		path, err := container2.KeyringFile(tokenConfig.Keyring)
+		if err != nil {
+			return err
+		}
+
+		storage := keyring.NewFile(path)
+
+		keys, err := storage.Load()
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if err := key.Parse(); err != nil {
+				return err
+			}
+		}
+
+		return action(tokenConfig, storage, keys)
+	})
+}
+
+// list writing keys without their material
+func (command *keysCommand) list(cmd *cobra.Command, keys []*keyring.Key) error {
+	now := time.Now().In(time.UTC)
+
+	type keyView struct {
+		ID         string     `json:"kid"`
+		Algorithm  string     `json:"alg"`
+		State      string     `json:"state"`
+		CreatedAt  time.Time  `json:"created_at"`
+		ActivateAt *time.Time `json:"activate_at,omitempty"`
+		RetireAt   *time.Time `json:"retire_at,omitempty"`
+	}
+
+	views := make([]*keyView, 0, len(keys))
+	rows := make([][]string, 0, len(keys))
+
+	for _, key := range keys {
+		activateAt, retireAt := "", ""
+		if key.ActivateAt != nil {
+			activateAt = key.ActivateAt.Format(time.RFC3339)
+		}
+
+		if key.RetireAt != nil {
+			retireAt = key.RetireAt.Format(time.RFC3339)
+		}
+
+		views = append(views, &keyView{
+			ID:         key.ID,
+			Algorithm:  key.Algorithm,
+			State:      key.State(now),
+			CreatedAt:  key.CreatedAt,
+			ActivateAt: key.ActivateAt,
+			RetireAt:   key.RetireAt,
+		})
+		rows = append(rows, []string{key.ID, key.Algorithm, key.State(now), key.CreatedAt.Format(time.RFC3339), activateAt, retireAt})
+	}
+
+	return writeOutput(cmd, command.output, views, []string{"KID", "ALG", "STATE", "CREATED", "ACTIVATE", "RETIRE"}, rows)
+}
+
+func marshalJwk(jwk map[string]interface{}, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.MarshalIndent(jwk, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(content, '\n'), nil
+}
@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"fmt"
+	container2 "github.com/Diez37/go-skeleton/infrastructure/container"
+	"github.com/diez37/go-packages/container"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+	"text/tabwriter"
+)
+
+// newMigrateCommand creating command group for migrations of database, it uses flags of db and migrator of root command
+func newMigrateCommand(container container.Container) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "migrations of database",
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up [N]",
+			Short: "applying all or N next migrations",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				migrator, err := container2.Migrator(container)
+				if err != nil {
+					return err
+				}
+
+				if len(args) == 0 {
+					err = migrator.Up()
+				} else {
+					var steps uint
+					if steps, err = cast.ToUintE(args[0]); err != nil {
+						return err
+					}
+
+					err = migrator.Steps(int(steps))
+				}
+
+				return printVersion(cmd, migrator, err)
+			},
+		},
+		&cobra.Command{
+			Use:   "down N",
+			Short: "rolling back N last migrations",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				steps, err := cast.ToUintE(args[0])
+				if err != nil {
+					return err
+				}
+
+				migrator, err := container2.Migrator(container)
+				if err != nil {
+					return err
+				}
+
+				return printVersion(cmd, migrator, migrator.Steps(-int(steps)))
+			},
+		},
+		&cobra.Command{
+			Use:   "version",
+			Short: "printing version of database",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				migrator, err := container2.Migrator(container)
+				if err != nil {
+					return err
+				}
+
+				return printVersion(cmd, migrator, nil)
+			},
+		},
+		&cobra.Command{
+			Use:   "force V",
+			Short: "setting version V without migrations and clearing dirty state",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				version, err := cast.ToIntE(args[0])
+				if err != nil {
+					return err
+				}
+
+				migrator, err := container2.Migrator(container)
+				if err != nil {
+					return err
+				}
+
+				return printVersion(cmd, migrator, migrator.Force(version))
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "printing migrations of source with state in database",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				migrator, err := container2.Migrator(container)
+				if err != nil {
+					return err
+				}
+
+				version, dirty, err := migrator.Version()
+				if err != nil && err != migrate.ErrNilVersion {
+					return err
+				}
+
+				versions, err := container2.Migrations(container)
+				if err != nil {
+					return err
+				}
+
+				writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				_, _ = fmt.Fprintln(writer, "VERSION\tSTATE")
+
+				for _, migration := range versions {
+					state := "pending"
+
+					switch {
+					case migration == version && dirty:
+						state = "dirty"
+					case migration <= version:
+						state = "applied"
+					}
+
+					_, _ = fmt.Fprintf(writer, "%d\t%s\n", migration, state)
+				}
+
+				return writer.Flush()
+			},
+		},
+	)
+
+	return cmd
+}
+
+// printVersion printing version of database after command, no change of migrations is not error
+func printVersion(cmd *cobra.Command, migrator *migrate.Migrate, err error) error {
+	if err != nil && err != migrate.ErrNoChange {
+		return err
+	}
+
+	version, dirty, err := migrator.Version()
+	if err == migrate.ErrNilVersion {
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), "version: none")
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "version: %d, dirty: %t\n", version, dirty)
+
+	return err
+}
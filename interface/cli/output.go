@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	OutputJson  = "json"
+	OutputTable = "table"
+)
+
+// outputFlag adding flag of format of output to command and its subcommands
+func outputFlag(cmd *cobra.Command, output *string) {
+	cmd.PersistentFlags().StringVarP(output, "output", "o", OutputTable, fmt.Sprintf(
+		"format of output, availably [%s]",
+		strings.Join([]string{OutputTable, OutputJson}, ","),
+	))
+}
+
+func checkOutput(output string) error {
+	if output != OutputTable && output != OutputJson {
+		return errors.New(fmt.Sprintf("cli: output '%s' unknown", output))
+	}
+
+	return nil
+}
+
+// writeOutput writing value as json or rows as table
+func writeOutput(cmd *cobra.Command, output string, value interface{}, header []string, rows [][]string) error {
+	if output == OutputJson {
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(value)
+	}
+
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, strings.Join(header, "\t"))
+
+	for _, row := range rows {
+		_, _ = fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	return writer.Flush()
+}
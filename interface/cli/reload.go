@@ -0,0 +1,146 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"github.com/Diez37/go-skeleton/application"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/diez37/go-packages/configurator"
+	"github.com/diez37/go-packages/log"
+	"github.com/spf13/pflag"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// configFile configurator which re-reads its config file, viper.Viper implements it
+type configFile interface {
+	ReadInConfig() error
+	ConfigFileUsed() string
+}
+
+// reload re-reading config file and reloading application.Policy on SIGHUP and on change of file,
+// the settings given by flags have priority over file, so they are not reloaded
+type reload struct {
+	policy       application.Policy
+	events       *config.Events
+	configurator configurator.Configurator
+	flags        *pflag.FlagSet
+	logger       log.Logger
+
+	mutex    sync.Mutex
+	modified time.Time
+}
+
+func newReload(policy application.Policy, events *config.Events, configurator configurator.Configurator, flags *pflag.FlagSet, logger log.Logger) *reload {
+	reload := &reload{policy: policy, events: events, configurator: configurator, flags: flags, logger: logger}
+	reload.modified = reload.modification()
+
+	return reload
+}
+
+// Serve reloading policy on every SIGHUP until ctx is done
+func (reload *reload) Serve(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			reload.logger.Info("reload: SIGHUP received")
+
+			if err := reload.Reload(); err != nil {
+				reload.logger.Errorf("reload: error - %s", err)
+			}
+		}
+	}
+}
+
+// Process reloading policy when config file was modified since the last reading
+func (reload *reload) Process(_ context.Context) error {
+	reload.mutex.Lock()
+	defer reload.mutex.Unlock()
+
+	if modified := reload.modification(); modified.IsZero() || !modified.After(reload.modified) {
+		return nil
+	}
+
+	reload.logger.Info("reload: config file changed")
+
+	return reload.reload()
+}
+
+// Reload re-reading config file and replacing policy, the invalid settings are refused and policy is kept
+func (reload *reload) Reload() error {
+	reload.mutex.Lock()
+	defer reload.mutex.Unlock()
+
+	return reload.reload()
+}
+
+func (reload *reload) reload() error {
+	file, ok := reload.configurator.(configFile)
+	if !ok {
+		return errors.New("reload: configurator has no config file")
+	}
+
+	reload.modified = reload.modification()
+
+	if err := file.ReadInConfig(); err != nil {
+		return err
+	}
+
+	next := *reload.policy.Get()
+
+	if !reload.flags.Changed(config.TokensAccessLifetimeFieldName) {
+		next.AccessLifetime = reload.configurator.GetDuration(config.TokensAccessLifetimeFieldName)
+	}
+
+	if !reload.flags.Changed(config.TokensMaximumTokensFieldName) {
+		next.MaximumTokens = reload.configurator.GetUint(config.TokensMaximumTokensFieldName)
+	}
+
+	if !reload.flags.Changed(config.TokensCheckFieldsForRefreshFieldName) {
+		next.RefreshCheckFields = reload.configurator.GetStringSlice(config.TokensCheckFieldsForRefreshFieldName)
+	}
+
+	if !reload.flags.Changed(config.TokensRefreshActionOnAccessViolation) {
+		next.AccessViolation = reload.configurator.GetString(config.TokensRefreshActionOnAccessViolation)
+	}
+
+	if err := next.Validate(reload.events); err != nil {
+		return err
+	}
+
+	diff := reload.policy.Reload(&next)
+	if len(diff) == 0 {
+		reload.logger.Info("reload: policy of tokens not changed")
+		return nil
+	}
+
+	for _, change := range diff {
+		reload.logger.Infof("reload: %s", change)
+	}
+
+	return nil
+}
+
+// modification return time of modification of config file, zero value when file is unknown
+func (reload *reload) modification() time.Time {
+	file, ok := reload.configurator.(configFile)
+	if !ok || file.ConfigFileUsed() == "" {
+		return time.Time{}
+	}
+
+	info, err := os.Stat(file.ConfigFileUsed())
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
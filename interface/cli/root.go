@@ -4,11 +4,17 @@ import (
 	"context"
 	"fmt"
 	"github.com/Diez37/go-skeleton/application"
+	"github.com/Diez37/go-skeleton/infrastructure/broker"
 	"github.com/Diez37/go-skeleton/infrastructure/config"
 	container2 "github.com/Diez37/go-skeleton/infrastructure/container"
+	"github.com/Diez37/go-skeleton/infrastructure/keyring"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
+	"github.com/Diez37/go-skeleton/infrastructure/publisher"
 	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/Diez37/go-skeleton/infrastructure/secret"
 	"github.com/Diez37/go-skeleton/interface/http"
 	"github.com/diez37/go-packages/app"
+	"github.com/diez37/go-packages/clients/db"
 	"github.com/diez37/go-packages/closer"
 	"github.com/diez37/go-packages/configurator"
 	bindFlags "github.com/diez37/go-packages/configurator/bind_flags"
@@ -16,7 +22,6 @@ import (
 	"github.com/diez37/go-packages/log"
 	"github.com/diez37/go-packages/repeater"
 	"github.com/golang-jwt/jwt"
-	"github.com/golang-migrate/migrate/v4"
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/multierr"
@@ -30,6 +35,13 @@ const (
 	AppName = "tokenizer"
 )
 
+// process repeatable process of application, after stop of repeater every process runs last time
+type process struct {
+	name    string
+	delay   time.Duration
+	process repeater.Process
+}
+
 // NewRootCommand creating, configuration and return cobra.Command for root command
 func NewRootCommand() (*cobra.Command, error) {
 	container := container.GetContainer()
@@ -39,53 +51,250 @@ func NewRootCommand() (*cobra.Command, error) {
 	}
 
 	cmd := &cobra.Command{
-		PreRunE: func(cmd *cobra.Command, _ []string) error {
-			return container.Invoke(func(generalConfig *app.Config, configurator configurator.Configurator) {
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			return container.Invoke(func(
+				generalConfig *app.Config,
+				tokenConfig *config.Token,
+				cookieConfig *config.Cookie,
+				configurator configurator.Configurator,
+			) {
 				app.Configuration(generalConfig, configurator, app.WithAppName(AppName))
+				tokenConfig.Configure(configurator)
+				cookieConfig.Configure(configurator)
 			})
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateTokens(container); err != nil {
+				return err
+			}
+
+			err := container.Invoke(func(cookieConfig *config.Cookie) error {
+				return cookieConfig.Validate()
+			})
+			if err != nil {
+				return err
+			}
+
 			return container.Invoke(func(
 				generalConfig *app.Config,
 				logger log.Logger,
 				closer closer.Closer,
-				migrator *migrate.Migrate,
-				repository repository.Repository,
+				storage repository.Repository,
+				dbConfig *db.Config,
+				lease repository.Lease,
+				outbox repository.Outbox,
+				tokenKeyring keyring.Watched,
+				resolver secret.Resolver,
 				tokenConfig *config.Token,
+				eventsConfig *config.Events,
+				profileConfig *config.Profile,
+				secretsConfig *config.Secrets,
+				migratorConfig *config.Migrator,
+				configurator configurator.Configurator,
 				repeatService repeater.Repeater,
+				metrics *metrics.Metrics,
 				tracer trace.Tracer,
 			) error {
 				logger.Infof("app: %s started", generalConfig.Name)
 				logger.Infof("app: pid - %d", generalConfig.PID)
 
-				if err := migrator.Up(); err != nil && err != migrate.ErrNoChange {
+				migratorConfig.Configure(configurator)
+
+				if migratorConfig.Skip {
+					logger.Info("app: migrations skipped")
+				} else if err := container2.Migrate(container); err != nil {
 					return err
 				}
 
 				ctx, cancelFunc := context.WithCancel(closer.GetContext())
 				defer cancelFunc()
 
+				if err := eventsConfig.Validate(profileConfig); err != nil {
+					return err
+				}
+
+				publishers, err := container2.Publishers(ctx, eventsConfig, profileConfig, resolver, logger, tracer)
+				if err != nil {
+					return err
+				}
+
+				eventPublishers := make(map[string]application.EventPublisher, len(publishers))
+				for destination, eventPublisher := range publishers {
+					eventPublishers[destination] = eventPublisher
+				}
+
+				defer func() {
+					for destination, eventPublisher := range publishers {
+						if err := eventPublisher.Close(); err != nil {
+							logger.Errorf("events: publisher '%s' close error - %s", destination, err)
+						}
+					}
+				}()
+
 				var errs error
 				wg := &sync.WaitGroup{}
 				mutex := &sync.Mutex{}
 
-				blocker := application.NewBlocker(repository, tracer)
-				saver := application.NewSaver(repository, tracer)
-				clear := application.NewClear(repository, tracer)
+				// repository is measured below write-behind, so histogram shows latency of storage
+				tokenStorage := repository.NewMeasured(storage, metrics.RepositoryDuration)
+				tokenRepository := tokenStorage
+
+				health := application.NewHealth(tracer)
+
+				ping, err := container2.Ping(container, dbConfig, configurator)
+				if err != nil {
+					return err
+				}
+
+				if ping != nil {
+					health.AddCheck("db", ping)
+				}
+
+				migrated, err := container2.Migrated(container, dbConfig, configurator)
+				if err != nil {
+					return err
+				}
+
+				if migrated != nil {
+					health.AddCheck("migrations", migrated)
+				}
+
+				processes := []process{}
+
+				// saver and blocker are flushed until http server finished every request,
+				// the requests waiting for space of buffers are woken up by their writes
+				flushCtx, stopFlush := context.WithCancel(context.Background())
+				defer stopFlush()
+
+				flushed := &sync.WaitGroup{}
+				flushProcesses := []process{}
+
+				if tokenConfig.Synchronous {
+					logger.Info("app: synchronous write mode, tokens are written to db before response")
+				} else {
+					writeBehind, err := newWriteBehind(tokenStorage, outbox, tokenConfig, logger, metrics, tracer)
+					if err != nil {
+						return err
+					}
+
+					defer func() {
+						if err := writeBehind.Close(); err != nil {
+							logger.Error(err)
+						}
+					}()
+
+					if err := writeBehind.cache.Restore(ctx); err != nil {
+						return err
+					}
+
+					tokenRepository = writeBehind.cache
+
+					health.AddCheck("buffers", writeBehind.Ready)
+
+					if tokenConfig.PeersBroker != "" {
+						tokenBroker, err := container2.Broker(container, tokenConfig.PeersBroker)
+						if err != nil {
+							return err
+						}
+
+						peers := application.NewPeers(writeBehind.cache, tokenBroker, tokenConfig, logger, tracer)
+						tokenRepository = peers
+
+						health.AddCheck("peers", peers.Ready)
+
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+
+							if err := peers.Serve(ctx); err != nil {
+								logger.Errorf("peers: error - %s", err)
+							}
+						}()
+					}
+
+					flushProcesses = append(flushProcesses,
+						process{name: "blocker", delay: tokenConfig.DelayBlocker, process: writeBehind.blocker},
+						process{name: "saver", delay: tokenConfig.DelaySaver, process: writeBehind.saver},
+					)
+
+					flushRepeater := repeater.New(logger)
+					for _, process := range flushProcesses {
+						flushRepeater.AddProcess(process.name, process.delay, application.NewMeasuredProcess(process.name, process.process, metrics))
+					}
+
+					flushed.Add(2)
+					go func() {
+						defer flushed.Done()
+
+						flushRepeater.Serve(flushCtx)
+					}()
+
+					go func() {
+						defer flushed.Done()
+
+						writeBehind.Flush(flushCtx)
+					}()
+				}
+
+				clear := application.NewClear(tokenStorage, tracer)
+				if tokenConfig.ClearLease > 0 {
+					clear = application.NewLeader("clear", tokenConfig.ClearLease, clear, lease, logger, tracer)
+				}
+
+				retention := application.NewRetention(tokenStorage, tokenConfig, tracer)
+				if tokenConfig.ClearLease > 0 {
+					retention = application.NewLeader("retention", tokenConfig.ClearLease, retention, lease, logger, tracer)
+				}
+
+				processes = append(processes,
+					process{name: "clear", delay: tokenConfig.DelayClear, process: clear},
+					process{name: "retention", delay: tokenConfig.DelayRetention, process: retention},
+				)
+
+				if len(eventPublishers) > 0 {
+					dispatcher := application.NewDispatcher(outbox, eventPublishers, eventsConfig, logger, tracer)
+					if tokenConfig.ClearLease > 0 {
+						dispatcher = application.NewLeader("events", tokenConfig.ClearLease, dispatcher, lease, logger, tracer)
+					}
+
+					processes = append(processes, process{name: "events", delay: eventsConfig.Delay, process: dispatcher})
+				}
+
+				events := application.NewEvents(outbox, eventsConfig.Destinations(), tracer)
 
 				jwt.TimeFunc = func() time.Time {
 					return time.Now().In(time.UTC)
 				}
 
+				policy := application.NewPolicy(tokenConfig)
+				reload := newReload(policy, eventsConfig, configurator, cmd.Flags(), logger)
+
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+
+					reload.Serve(ctx)
+				}()
+
+				health.Start()
+
+				// served is closed when http server finished every request
+				served := make(chan struct{})
+
 				wg.Add(1)
 				go func() {
 					defer wg.Done()
+					defer close(served)
 
 					err := http.Serve(
 						ctx,
 						container,
 						logger,
-						application.NewToken(tokenConfig, logger, saver, saver, blocker, tracer),
+						application.NewMeasuredToken(
+							application.NewToken(policy, logger, tokenRepository, tokenRepository, tokenRepository, events, tokenKeyring, metrics, tracer),
+							metrics,
+						),
+						health,
 						tracer,
 					)
 					if err != nil {
@@ -94,7 +303,7 @@ func NewRootCommand() (*cobra.Command, error) {
 						mutex.Lock()
 						defer mutex.Unlock()
 
-						errs = multierr.Append(errs, errs)
+						errs = multierr.Append(errs, err)
 					}
 				}()
 
@@ -102,39 +311,44 @@ func NewRootCommand() (*cobra.Command, error) {
 				go func() {
 					defer wg.Done()
 
-					repeatService.
-						AddProcess("blocker", tokenConfig.DelayBlocker, blocker).
-						AddProcess("saver", tokenConfig.DelaySaver, saver).
-						AddProcess("clear", tokenConfig.DelayClear, clear).
-						Serve(ctx)
-
-					ctx, cancelFunc := context.WithTimeout(context.Background(), time.Minute)
-					defer cancelFunc()
+					for _, process := range processes {
+						repeatService.AddProcess(process.name, process.delay, application.NewMeasuredProcess(process.name, process.process, metrics))
+					}
 
-					if err := blocker.Process(ctx); err != nil {
-						mutex.Lock()
-						defer mutex.Unlock()
+					// keyring and policy are re-read only while serving, they have no last run
+					if secretsConfig.Delay > 0 {
+						repeatService.AddProcess("keyring", secretsConfig.Delay, application.NewMeasuredProcess("keyring", tokenKeyring, metrics))
+					}
 
-						errs = multierr.Append(errs, errs)
+					if tokenConfig.ReloadDelay > 0 {
+						repeatService.AddProcess("reload", tokenConfig.ReloadDelay, application.NewMeasuredProcess("reload", reload, metrics))
 					}
 
-					if err := saver.Process(ctx); err != nil {
-						mutex.Lock()
-						defer mutex.Unlock()
+					repeatService.Serve(ctx)
 
-						errs = multierr.Append(errs, errs)
-					}
+					// the last writes start after the service became not ready and http server finished every request
+					health.Stop()
+					<-served
 
-					if err := clear.Process(ctx); err != nil {
-						mutex.Lock()
-						defer mutex.Unlock()
+					stopFlush()
+					flushed.Wait()
+
+					ctx, cancelFunc := context.WithTimeout(context.Background(), time.Minute)
+					defer cancelFunc()
 
-						errs = multierr.Append(errs, errs)
+					for _, process := range append(flushProcesses, processes...) {
+						if err := process.process.Process(ctx); err != nil {
+							mutex.Lock()
+							errs = multierr.Append(errs, err)
+							mutex.Unlock()
+						}
 					}
 				}()
 
 				wg.Wait()
-				return errs
+
+				// the storage is closed after the final flush of saver, blocker and outbox
+				return multierr.Append(errs, container2.CloseStorage(container, dbConfig, configurator))
 			})
 		},
 	}
@@ -145,34 +359,144 @@ func NewRootCommand() (*cobra.Command, error) {
 		bindFlags.Tracer,
 		bindFlags.DataBase,
 		bindFlags.Migrator,
+		bindFlags.CacheOnlyRedis,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	err = container.Invoke(func(profileConfig *config.Profile) {
+		cmd.PersistentFlags().StringVar(&profileConfig.Name, config.ProfileFieldName, config.ProfileDefault, fmt.Sprintf(
+			"environment of deployment, availably [%s], profiles except '%s' refuse missing, default and weak secrets and keys",
+			strings.Join(config.Profiles, ","), config.ProfileDev,
+		))
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	err = container.Invoke(func(tokenConfig *config.Token) {
-		cmd.PersistentFlags().StringVar(&tokenConfig.Secret, config.TokensSecretFieldName, config.TokensSecretDefault, "")
+		cmd.PersistentFlags().StringVar(&tokenConfig.Secret, config.TokensSecretFieldName, config.TokensSecretDefault, "secret of HMAC signature of tokens or its reference 'file://path', 'env://NAME' or 'vault://path#field'")
+		cmd.PersistentFlags().StringVar(&tokenConfig.Keyring, config.TokensKeyringFieldName, "", "path to file of keys of signing managed by command 'keys' or its reference 'env://NAME' or 'vault://path#field', empty value signs tokens by secret")
 		cmd.PersistentFlags().UintVar(&tokenConfig.MaximumTokens, config.TokensMaximumTokensFieldName, config.TokensMaximumTokensDefault, "maximum tokens on one account")
 		cmd.PersistentFlags().DurationVar(&tokenConfig.DelayClear, config.TokensDelayClearFieldName, config.TokensDelayClearDefault, "")
+		cmd.PersistentFlags().DurationVar(&tokenConfig.DelayRetention, config.TokensDelayRetentionFieldName, config.TokensDelayRetentionDefault, "delay between purges of archive")
+		cmd.PersistentFlags().DurationVar(&tokenConfig.ArchiveRetention, config.TokensArchiveRetentionFieldName, config.TokensArchiveRetentionDefault, "lifetime of revoked and expired tokens in archive, zero value keeps archive forever")
+		cmd.PersistentFlags().DurationVar(&tokenConfig.ClearLease, config.TokensClearLeaseFieldName, 0, "lifetime of lease for running clear, retention and delivery of events on one replica, must be longer than their delays, zero value disabled election")
 		cmd.PersistentFlags().DurationVar(&tokenConfig.AccessLifetime, config.TokensAccessLifetimeFieldName, config.TokensAccessLifetimeDefault, "")
 		cmd.PersistentFlags().DurationVar(&tokenConfig.RefreshLifetime, config.TokensRefreshLifetimeFieldName, config.TokensRefreshLifetimeDefault, "")
 		cmd.PersistentFlags().DurationVar(&tokenConfig.DelayBlocker, config.TokensDelayBlockerFieldName, config.TokensDelayBlockerDefault, "")
+		cmd.PersistentFlags().StringVar(&tokenConfig.BlockerJournal, config.TokensBlockerJournalFieldName, "", "path to journal of blocks not yet applied to db, empty value disabled journal")
 		cmd.PersistentFlags().DurationVar(&tokenConfig.DelaySaver, config.TokensDelaySaverFieldName, config.TokensDelaySaverDefault, "")
-		cmd.PersistentFlags().StringSliceVar(&tokenConfig.RefreshCheckFields, config.TokensCheckFieldsForRefreshFieldName, config.TokensCheckFieldsForRefresh, "")
+		cmd.PersistentFlags().StringVar(&tokenConfig.SaverJournal, config.TokensSaverJournalFieldName, "", "path to journal of new tokens not yet saved to db, empty value disabled journal")
+		cmd.PersistentFlags().BoolVar(&tokenConfig.Synchronous, config.TokensSynchronousFieldName, config.TokensSynchronousDefault, "writing tokens to db before response, instead of buffers of saver and blocker")
+		cmd.PersistentFlags().StringVar(&tokenConfig.PeersBroker, config.TokensPeersBrokerFieldName, "", fmt.Sprintf(
+			"broker for sharing not yet written tokens with other replicas, availably [%s], empty value disabled sharing",
+			strings.Join([]string{broker.RedisBroker}, ","),
+		))
+		cmd.PersistentFlags().DurationVar(&tokenConfig.PeersTTL, config.TokensPeersTTLFieldName, config.TokensPeersTTLDefault, "lifetime of token received from other replica, must be longer than delay of saver")
+		cmd.PersistentFlags().UintVar(&tokenConfig.SaverLimit, config.TokensSaverLimitFieldName, config.TokensSaverLimitDefault, "maximum number of tokens waiting for save, zero value disabled limit")
+		cmd.PersistentFlags().UintVar(&tokenConfig.BlockerLimit, config.TokensBlockerLimitFieldName, config.TokensBlockerLimitDefault, "maximum number of blocks waiting for apply, zero value disabled limit")
+		cmd.PersistentFlags().UintVar(&tokenConfig.ReadySaver, config.TokensReadySaverFieldName, 0, "maximum number of tokens waiting for save of ready service, zero value uses limit of saver")
+		cmd.PersistentFlags().UintVar(&tokenConfig.ReadyBlocker, config.TokensReadyBlockerFieldName, 0, "maximum number of blocks waiting for apply of ready service, zero value uses limit of blocker")
+		cmd.PersistentFlags().DurationVar(&tokenConfig.ReadyDrain, config.TokensReadyDrainFieldName, config.TokensReadyDrainDefault, "pause between not ready service and shutdown of http server on shutdown, zero value shuts down immediately")
+		cmd.PersistentFlags().UintVar(&tokenConfig.BufferFlush, config.TokensBufferFlushFieldName, config.TokensBufferFlushDefault, "size of buffer which is written without waiting of delay, zero value disabled it")
+		cmd.PersistentFlags().UintVar(&tokenConfig.BufferChunk, config.TokensBufferChunkFieldName, config.TokensBufferChunkDefault, "maximum number of tokens in one request to db, zero value disabled chunks")
+		cmd.PersistentFlags().StringVar(
+			&tokenConfig.BufferOverflow,
+			config.TokensBufferOverflowFieldName,
+			config.TokensBufferOverflowDefault,
+			fmt.Sprintf("action on full buffer, availably [%s]", strings.Join(config.TokensBufferOverflowActions, ",")),
+		)
+		cmd.PersistentFlags().StringSliceVar(&tokenConfig.RefreshCheckFields, config.TokensCheckFieldsForRefreshFieldName, config.TokensCheckFieldsForRefresh, fmt.Sprintf(
+			"fields of token which must be equal on refresh, availably [%s]",
+			strings.Join(config.TokenRefreshFields, ","),
+		))
+		cmd.PersistentFlags().DurationVar(&tokenConfig.ReloadDelay, config.TokensReloadDelayFieldName, config.TokensReloadDelayDefault, fmt.Sprintf(
+			"delay between checks of change of config file, the settings '%s', '%s', '%s' and '%s' are reloaded on change and SIGHUP, zero value reloads only on SIGHUP",
+			config.TokensAccessLifetimeFieldName,
+			config.TokensMaximumTokensFieldName,
+			config.TokensCheckFieldsForRefreshFieldName,
+			config.TokensRefreshActionOnAccessViolation,
+		))
 		cmd.PersistentFlags().StringVar(
 			&tokenConfig.AccessViolation,
 			config.TokensRefreshActionOnAccessViolation,
 			config.TokensAccessViolationActionDefault,
-			fmt.Sprintf("availably [%s]", strings.Join([]string{
-				config.TokensAccessViolationActionDisableAll,
-				config.TokensAccessViolationActionDisableCurrent,
-				config.TokensAccessViolationActionNone,
-			}, ",")),
+			fmt.Sprintf("availably [%s]", strings.Join(config.TokensAccessViolationActions, ",")),
 		)
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	err = container.Invoke(func(cookieConfig *config.Cookie) {
+		cmd.PersistentFlags().StringVar(&cookieConfig.Name, config.CookieNameFieldName, config.CookieNameDefault, "name of cookie of refresh token without prefix")
+		cmd.PersistentFlags().StringVar(&cookieConfig.Path, config.CookiePathFieldName, config.CookiePathDefault, "path of cookie of refresh token")
+		cmd.PersistentFlags().StringVar(&cookieConfig.Domain, config.CookieDomainFieldName, "", "domain of cookie of refresh token shared with subdomains, empty value limits cookie to host")
+		cmd.PersistentFlags().BoolVar(&cookieConfig.Secure, config.CookieSecureFieldName, config.CookieSecureDefault, "sending cookie of refresh token only over HTTPS")
+		cmd.PersistentFlags().BoolVar(&cookieConfig.HttpOnly, config.CookieHttpOnlyFieldName, config.CookieHttpOnlyDefault, "hiding cookie of refresh token from scripts")
+		cmd.PersistentFlags().StringVar(&cookieConfig.SameSite, config.CookieSameSiteFieldName, config.CookieSameSiteDefault, fmt.Sprintf(
+			"attribute SameSite of cookie of refresh token, availably [%s]",
+			strings.Join(config.CookieSameSites, ","),
+		))
+		cmd.PersistentFlags().BoolVar(&cookieConfig.HostPrefix, config.CookieHostPrefixFieldName, config.CookieHostPrefixDefault, fmt.Sprintf(
+			"adding prefix '%s' to name of cookie, it requires secure cookie with path '/' and without domain",
+			config.CookieHostPrefix,
+		))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = container.Invoke(func(eventsConfig *config.Events) {
+		cmd.PersistentFlags().StringSliceVar(&eventsConfig.Webhooks, config.EventsWebhooksFieldName, nil, "urls of webhooks which receive security events of sessions, empty value disabled events")
+		cmd.PersistentFlags().StringVar(&eventsConfig.Secret, config.EventsSecretFieldName, "", "secret key of HMAC-SHA256 signature of webhook requests or its reference 'file://path', 'env://NAME' or 'vault://path#field'")
+		cmd.PersistentFlags().StringSliceVar(&eventsConfig.Publishers, config.EventsPublishersFieldName, nil, fmt.Sprintf(
+			"publishers which receive security events of sessions, availably [%s]",
+			strings.Join([]string{publisher.StdoutPublisher, publisher.NatsPublisher, publisher.KafkaPublisher}, ","),
+		))
+		cmd.PersistentFlags().StringVar(&eventsConfig.NatsUrl, config.EventsNatsUrlFieldName, config.EventsNatsUrlDefault, "url of server of publisher 'nats'")
+		cmd.PersistentFlags().StringVar(&eventsConfig.NatsSubject, config.EventsNatsSubjectFieldName, config.EventsNatsSubjectDefault, "prefix of subject of publisher 'nats', subject of event is 'prefix.type'")
+		cmd.PersistentFlags().StringSliceVar(&eventsConfig.KafkaBrokers, config.EventsKafkaBrokersFieldName, []string{config.EventsKafkaBrokersDefault}, "brokers of publisher 'kafka'")
+		cmd.PersistentFlags().StringVar(&eventsConfig.KafkaTopic, config.EventsKafkaTopicFieldName, config.EventsKafkaTopicDefault, "topic of publisher 'kafka'")
+		cmd.PersistentFlags().DurationVar(&eventsConfig.Delay, config.EventsDelayFieldName, config.EventsDelayDefault, "delay between deliveries of events")
+		cmd.PersistentFlags().UintVar(&eventsConfig.Batch, config.EventsBatchFieldName, config.EventsBatchDefault, "maximum number of events in one delivery")
+		cmd.PersistentFlags().UintVar(&eventsConfig.Attempts, config.EventsAttemptsFieldName, config.EventsAttemptsDefault, "number of attempts of delivery, after the last one event is dropped")
+		cmd.PersistentFlags().DurationVar(&eventsConfig.BackoffInitial, config.EventsBackoffInitialFieldName, config.EventsBackoffInitialDefault, "delay before the first retry of delivery, it doubles for every next retry")
+		cmd.PersistentFlags().DurationVar(&eventsConfig.BackoffMaximum, config.EventsBackoffMaximumFieldName, config.EventsBackoffMaximumDefault, "maximum delay between retries of delivery")
+		cmd.PersistentFlags().DurationVar(&eventsConfig.Timeout, config.EventsTimeoutFieldName, config.EventsTimeoutDefault, "timeout of one delivery of event")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = container.Invoke(func(secretsConfig *config.Secrets) {
+		cmd.PersistentFlags().DurationVar(&secretsConfig.Delay, config.SecretsDelayFieldName, config.SecretsDelayDefault, "delay between re-readings of secret and keyring of tokens, zero value disabled re-reading")
+		cmd.PersistentFlags().DurationVar(&secretsConfig.Timeout, config.SecretsTimeoutFieldName, config.SecretsTimeoutDefault, "timeout of reading of secret from Vault")
+		cmd.PersistentFlags().StringVar(&secretsConfig.VaultAddress, config.SecretsVaultAddressFieldName, "", "address of Vault, e.g. 'https://vault:8200', empty value disabled references 'vault://'")
+		cmd.PersistentFlags().StringVar(&secretsConfig.VaultToken, config.SecretsVaultTokenFieldName, "", "token of Vault or its reference 'file://path' or 'env://NAME'")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = container.Invoke(func(migratorConfig *config.Migrator) {
+		cmd.Flags().BoolVar(&migratorConfig.Skip, config.MigratorSkipFieldName, config.MigratorSkipDefault, "not applying migrations on start, they are applied by command 'migrate up'")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = container.Invoke(func(boltConfig *config.Bolt) {
+		cmd.PersistentFlags().StringVar(&boltConfig.Path, config.BoltPathFieldName, config.BoltPathDefault, "path to file of embedded storage for driver 'bolt'")
+		cmd.PersistentFlags().DurationVar(&boltConfig.Timeout, config.BoltTimeoutFieldName, config.BoltTimeoutDefault, "timeout for obtaining file lock of embedded storage")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cmd.AddCommand(newMigrateCommand(container), newTokenCommand(container), newKeysCommand(container), newConfigCommand(container))
+
 	return cmd, nil
 }
@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/Diez37/go-skeleton/application"
+	"github.com/Diez37/go-skeleton/domain"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/keyring"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/container"
+	"github.com/diez37/go-packages/log"
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/trace"
+	"net"
+	"time"
+)
+
+// tokenSession refresh token in output of sessions, revoke_reason is set for revoked token
+type tokenSession struct {
+	UUID         uuid.UUID  `json:"uuid"`
+	Fingerprint  string     `json:"fingerprint"`
+	Ip           string     `json:"ip"`
+	UserAgent    string     `json:"user_agent"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresIn    time.Time  `json:"expires_in"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason string     `json:"revoke_reason,omitempty"`
+}
+
+// tokenCommand dependencies of subcommands of token
+type tokenCommand struct {
+	container container.Container
+	output    string
+}
+
+// newTokenCommand creating command group for tokens, the tokens are written to db directly as in synchronous mode
+func newTokenCommand(container container.Container) *cobra.Command {
+	command := &tokenCommand{container: container}
+
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "issuing, inspecting and revoking tokens",
+	}
+
+	outputFlag(cmd, &command.output)
+
+	var login, ip, fingerprint, userAgent string
+
+	issue := &cobra.Command{
+		Use:   "issue",
+		Short: "issuing pair of access and refresh tokens for login",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			loginUUID, err := uuid.Parse(login)
+			if err != nil {
+				return err
+			}
+
+			return command.run(cmd, func(ctx context.Context, service application.Token, _ repository.Finder) error {
+				refreshToken, accessToken, err := service.Create(ctx, &domain.RefreshToken{
+					Login:       loginUUID,
+					Ip:          net.ParseIP(ip),
+					Fingerprint: fingerprint,
+					UserAgent:   userAgent,
+				})
+				if err != nil {
+					return err
+				}
+
+				return writeOutput(cmd, command.output, map[string]interface{}{
+					"login":   refreshToken.Login,
+					"refresh": refreshToken.UUID,
+					"access":  accessToken,
+				}, []string{"LOGIN", "REFRESH", "ACCESS"}, [][]string{
+					{refreshToken.Login.String(), refreshToken.UUID.String(), accessToken},
+				})
+			})
+		},
+	}
+	issue.Flags().StringVar(&login, "login", "", "login of tokens")
+	issue.Flags().StringVar(&ip, "ip", "127.0.0.1", "ip of client of refresh token")
+	issue.Flags().StringVar(&fingerprint, "fingerprint", "", "fingerprint of client of refresh token")
+	issue.Flags().StringVar(&userAgent, "user-agent", AppName, "user agent of client of refresh token")
+	_ = issue.MarkFlagRequired("login")
+
+	revokeAll := &cobra.Command{
+		Use:   "revoke-all",
+		Short: "revoking all refresh tokens of login",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			loginUUID, err := uuid.Parse(login)
+			if err != nil {
+				return err
+			}
+
+			return command.run(cmd, func(ctx context.Context, service application.Token, _ repository.Finder) error {
+				if err := service.DisableAll(ctx, repository.RevokeReasonAdmin, loginUUID); err != nil {
+					return err
+				}
+
+				return writeOutput(cmd, command.output, map[string]interface{}{"login": loginUUID, "revoked": true}, []string{"LOGIN", "REVOKED"}, [][]string{
+					{loginUUID.String(), "true"},
+				})
+			})
+		},
+	}
+	revokeAll.Flags().StringVar(&login, "login", "", "login of tokens")
+	_ = revokeAll.MarkFlagRequired("login")
+
+	sessions := &cobra.Command{
+		Use:   "sessions",
+		Short: "printing refresh tokens of login including revoked and archived with reason of revoke",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			loginUUID, err := uuid.Parse(login)
+			if err != nil {
+				return err
+			}
+
+			return command.run(cmd, func(ctx context.Context, service application.Token, _ repository.Finder) error {
+				tokens, err := service.Sessions(ctx, loginUUID)
+				if err != nil {
+					return err
+				}
+
+				sessions := make([]*tokenSession, 0, len(tokens))
+				rows := make([][]string, 0, len(tokens))
+				for _, token := range tokens {
+					sessions = append(sessions, &tokenSession{
+						UUID:         token.UUID,
+						Fingerprint:  token.Fingerprint,
+						Ip:           token.Ip,
+						UserAgent:    token.UserAgent,
+						CreatedAt:    token.CreatedAt,
+						ExpiresIn:    token.ExpiresIn,
+						RevokedAt:    token.RevokedAt,
+						RevokeReason: token.RevokeReason,
+					})
+
+					revokedAt := ""
+					if token.RevokedAt != nil {
+						revokedAt = token.RevokedAt.Format(time.RFC3339)
+					}
+
+					rows = append(rows, []string{
+						token.UUID.String(),
+						token.CreatedAt.Format(time.RFC3339),
+						token.ExpiresIn.Format(time.RFC3339),
+						revokedAt,
+						token.RevokeReason,
+						token.Ip,
+						token.UserAgent,
+					})
+				}
+
+				return writeOutput(cmd, command.output, sessions, []string{"UUID", "CREATED", "EXPIRES", "REVOKED", "REASON", "IP", "USER AGENT"}, rows)
+			})
+		},
+	}
+	sessions.Flags().StringVar(&login, "login", "", "login of tokens")
+	_ = sessions.MarkFlagRequired("login")
+
+	cmd.AddCommand(
+		issue,
+		&cobra.Command{
+			Use:   "decode JWT",
+			Short: "verifying access token with configured secret and printing its claims",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return command.run(cmd, func(ctx context.Context, service application.Token, _ repository.Finder) error {
+					claims, err := service.Parse(ctx, args[0])
+					if err != nil {
+						return err
+					}
+
+					valid, reason := true, ""
+					if err := service.Validation(ctx, args[0]); err != nil {
+						valid, reason = false, err.Error()
+					}
+
+					return writeOutput(cmd, command.output, map[string]interface{}{
+						"login":      claims.Login,
+						"expires_in": claims.ExpiresIn,
+						"valid":      valid,
+						"error":      reason,
+					}, []string{"LOGIN", "EXPIRES", "VALID", "ERROR"}, [][]string{
+						{claims.Login.String(), claims.ExpiresIn.Format(time.RFC3339), fmt.Sprint(valid), reason},
+					})
+				})
+			},
+		},
+		&cobra.Command{
+			Use:   "revoke UUID",
+			Short: "revoking refresh token by administrator",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				tokenUUID, err := uuid.Parse(args[0])
+				if err != nil {
+					return err
+				}
+
+				return command.run(cmd, func(ctx context.Context, service application.Token, _ repository.Finder) error {
+					if err := service.Disable(ctx, repository.RevokeReasonAdmin, tokenUUID); err != nil {
+						return err
+					}
+
+					return writeOutput(cmd, command.output, map[string]interface{}{"uuid": tokenUUID, "revoked": true}, []string{"UUID", "REVOKED"}, [][]string{
+						{tokenUUID.String(), "true"},
+					})
+				})
+			},
+		},
+		revokeAll,
+		sessions,
+	)
+
+	return cmd
+}
+
+// run resolving token service from container and running action with it,
+// the events of action are written to outbox and delivered by server
+func (command *tokenCommand) run(
+	cmd *cobra.Command,
+	action func(ctx context.Context, service application.Token, finder repository.Finder) error,
+) error {
+	if err := checkOutput(command.output); err != nil {
+		return err
+	}
+
+	if err := validateTokens(command.container); err != nil {
+		return err
+	}
+
+	return command.container.Invoke(func(
+		tokenRepository repository.Repository,
+		outbox repository.Outbox,
+		tokenKeyring keyring.Keyring,
+		tokenConfig *config.Token,
+		eventsConfig *config.Events,
+		logger log.Logger,
+		metrics *metrics.Metrics,
+		tracer trace.Tracer,
+	) error {
+		jwt.TimeFunc = func() time.Time {
+			return time.Now().In(time.UTC)
+		}
+
+		events := application.NewEvents(outbox, eventsConfig.Destinations(), tracer)
+		service := application.NewToken(application.NewPolicy(tokenConfig), logger, tokenRepository, tokenRepository, tokenRepository, events, tokenKeyring, metrics, tracer)
+
+		return action(cmd.Context(), service, tokenRepository)
+	})
+}
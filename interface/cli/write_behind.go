@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Diez37/go-skeleton/application"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/journal"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/log"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/multierr"
+)
+
+// writeBehind saver and blocker with their journals, reads and writes of tokens go through cache
+type writeBehind struct {
+	saver   application.Saver
+	blocker application.Blocker
+	cache   application.Cache
+
+	journals []journal.Journal
+
+	// saverReady and blockerReady maximum sizes of buffers of ready service, zero value disabled check
+	saverReady   int
+	blockerReady int
+
+	logger  log.Logger
+	metrics *metrics.Metrics
+}
+
+func newWriteBehind(
+	tokenRepository repository.Repository,
+	outbox repository.Outbox,
+	tokenConfig *config.Token,
+	logger log.Logger,
+	metrics *metrics.Metrics,
+	tracer trace.Tracer,
+) (*writeBehind, error) {
+	saverJournal, err := journal.New(tokenConfig.SaverJournal)
+	if err != nil {
+		return nil, err
+	}
+
+	blockerJournal, err := journal.New(tokenConfig.BlockerJournal)
+	if err != nil {
+		return nil, multierr.Append(err, saverJournal.Close())
+	}
+
+	saver := application.NewSaver(tokenRepository, saverJournal, tokenConfig, logger, metrics, tracer)
+	blocker := application.NewBlocker(tokenRepository, blockerJournal, tokenConfig, logger, metrics, tracer)
+
+	saverReady, blockerReady := tokenConfig.ReadySaver, tokenConfig.ReadyBlocker
+	if saverReady == 0 {
+		saverReady = tokenConfig.SaverLimit
+	}
+
+	if blockerReady == 0 {
+		blockerReady = tokenConfig.BlockerLimit
+	}
+
+	return &writeBehind{
+		saver:        saver,
+		blocker:      blocker,
+		cache:        application.NewCache(saver, blocker, outbox, tracer),
+		journals:     []journal.Journal{saverJournal, blockerJournal},
+		saverReady:   int(saverReady),
+		blockerReady: int(blockerReady),
+		logger:       logger,
+		metrics:      metrics,
+	}, nil
+}
+
+// Flush writing buffers which reached size of flush until ctx is done
+func (writeBehind *writeBehind) Flush(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-writeBehind.saver.Flush():
+			writeBehind.logger.Info("saver: buffer reached size of flush")
+
+			if err := writeBehind.saver.Process(ctx); err != nil {
+				writeBehind.metrics.ProcessFailures.WithLabelValues("saver").Inc()
+				writeBehind.logger.Errorf("saver: flush error - %s", err)
+			}
+		case <-writeBehind.blocker.Flush():
+			writeBehind.logger.Info("blocker: queue reached size of flush")
+
+			if err := writeBehind.blocker.Process(ctx); err != nil {
+				writeBehind.metrics.ProcessFailures.WithLabelValues("blocker").Inc()
+				writeBehind.logger.Errorf("blocker: flush error - %s", err)
+			}
+		}
+	}
+}
+
+// Ready checking that buffers are under their sizes of ready service
+func (writeBehind *writeBehind) Ready(_ context.Context) error {
+	if size := writeBehind.saver.Size(); writeBehind.saverReady > 0 && size >= writeBehind.saverReady {
+		return errors.New(fmt.Sprintf("saver: %d tokens waiting for save, maximum - %d", size, writeBehind.saverReady))
+	}
+
+	if size := writeBehind.blocker.Size(); writeBehind.blockerReady > 0 && size >= writeBehind.blockerReady {
+		return errors.New(fmt.Sprintf("blocker: %d blocks waiting for apply, maximum - %d", size, writeBehind.blockerReady))
+	}
+
+	return nil
+}
+
+func (writeBehind *writeBehind) Close() error {
+	var errs error
+
+	for _, journal := range writeBehind.journals {
+		errs = multierr.Append(errs, journal.Close())
+	}
+
+	return errs
+}
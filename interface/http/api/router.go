@@ -12,14 +12,14 @@ import (
 
 func Router(
 	logger log.Logger,
-	config *config.Token,
+	cookie *config.Cookie,
 	service application.Token,
 	validator *validator.Validate,
 	tracer trace.Tracer,
 ) chi.Router {
 	router := chi.NewRouter()
 
-	router.Mount("/api", v1.Router(logger, config, service, validator, tracer))
+	router.Mount("/api", v1.Router(logger, cookie, service, validator, tracer))
 
 	return router
 }
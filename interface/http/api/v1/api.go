@@ -6,6 +6,7 @@ import (
 	"github.com/Diez37/go-skeleton/application"
 	"github.com/Diez37/go-skeleton/domain"
 	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
 	"github.com/diez37/go-packages/log"
 	"github.com/go-http-utils/headers"
 	"github.com/go-playground/validator/v10"
@@ -27,10 +28,11 @@ type API interface {
 
 	Validation(writer http.ResponseWriter, request *http.Request)
 	DeleteAll(writer http.ResponseWriter, request *http.Request)
+	Sessions(writer http.ResponseWriter, request *http.Request)
 }
 
 type api struct {
-	config *config.Token
+	cookie *config.Cookie
 
 	logger    log.Logger
 	service   application.Token
@@ -39,13 +41,13 @@ type api struct {
 }
 
 func NewApi(
-	config *config.Token,
+	cookie *config.Cookie,
 	logger log.Logger,
 	service application.Token,
 	validator *validator.Validate,
 	tracer trace.Tracer,
 ) API {
-	return &api{config: config, logger: logger, service: service, validator: validator, tracer: tracer}
+	return &api{cookie: cookie, logger: logger, service: service, validator: validator, tracer: tracer}
 }
 
 func (api *api) Create(writer http.ResponseWriter, request *http.Request) {
@@ -81,18 +83,11 @@ func (api *api) Create(writer http.ResponseWriter, request *http.Request) {
 		UserAgent:   ctx.Value(UserAgentFieldName).(string),
 	})
 	if err != nil {
-		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		api.logger.Error(err)
+		api.serviceError(writer, err)
 		return
 	}
 
-	http.SetCookie(writer, &http.Cookie{
-		Name:     RefreshTokenFieldName,
-		Value:    refreshToken.UUID.String(),
-		Path:     request.RequestURI,
-		Expires:  time.Now().In(time.UTC).Add(api.config.RefreshLifetime),
-		HttpOnly: true,
-	})
+	api.setCookie(writer, refreshToken.UUID.String(), refreshToken.ExpiresIn)
 
 	writer.Header().Add(headers.Authorization, fmt.Sprintf("%s %s", BearerAuthorizationType, accessToken))
 	writer.WriteHeader(http.StatusOK)
@@ -106,7 +101,7 @@ func (api *api) Read(writer http.ResponseWriter, request *http.Request) {
 
 	token, err := api.service.Parse(ctx, ctx.Value(AccessTokenFieldName).(string))
 	if err != nil {
-		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		http.Error(writer, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 		api.logger.Error(err)
 		return
 	}
@@ -165,18 +160,11 @@ func (api *api) Update(writer http.ResponseWriter, request *http.Request) {
 		UserAgent:   ctx.Value(UserAgentFieldName).(string),
 	})
 	if err != nil {
-		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		api.logger.Error(err)
+		api.serviceError(writer, err)
 		return
 	}
 
-	http.SetCookie(writer, &http.Cookie{
-		Name:     RefreshTokenFieldName,
-		Value:    refreshToken.UUID.String(),
-		Path:     request.RequestURI,
-		Expires:  time.Now().In(time.UTC).Add(api.config.RefreshLifetime),
-		HttpOnly: true,
-	})
+	api.setCookie(writer, refreshToken.UUID.String(), refreshToken.ExpiresIn)
 
 	writer.Header().Add(headers.Authorization, fmt.Sprintf("%s %s", BearerAuthorizationType, accessToken))
 	writer.WriteHeader(http.StatusOK)
@@ -188,18 +176,12 @@ func (api *api) Delete(writer http.ResponseWriter, request *http.Request) {
 
 	span.SetAttributes(attribute.Int("version", 1))
 
-	if err := api.service.Disable(ctx, ctx.Value(RefreshTokenFieldName).(uuid.UUID)); err != nil {
-		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		api.logger.Error(err)
+	if err := api.service.Disable(ctx, repository.RevokeReasonLogout, ctx.Value(RefreshTokenFieldName).(uuid.UUID)); err != nil {
+		api.serviceError(writer, err)
 		return
 	}
 
-	http.SetCookie(writer, &http.Cookie{
-		Name:     RefreshTokenFieldName,
-		Path:     request.RequestURI,
-		Expires:  time.Now().In(time.UTC),
-		HttpOnly: true,
-	})
+	api.clearCookie(writer)
 
 	writer.WriteHeader(http.StatusAccepted)
 }
@@ -227,16 +209,93 @@ func (api *api) DeleteAll(writer http.ResponseWriter, request *http.Request) {
 
 	accessToken, err := api.service.Parse(ctx, ctx.Value(AccessTokenFieldName).(string))
 	if err != nil {
-		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		http.Error(writer, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 		api.logger.Error(err)
 		return
 	}
 
-	if err := api.service.DisableAll(ctx, accessToken.Login, ctx.Value(RefreshTokenFieldName).(uuid.UUID)); err != nil {
+	if err := api.service.DisableAll(ctx, repository.RevokeReasonLogout, accessToken.Login, ctx.Value(RefreshTokenFieldName).(uuid.UUID)); err != nil {
+		api.serviceError(writer, err)
+		return
+	}
+
+	api.clearCookie(writer)
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// Sessions writing sessions of login of access token including revoked and archived with reason of revoke
+func (api *api) Sessions(writer http.ResponseWriter, request *http.Request) {
+	ctx, span := api.tracer.Start(request.Context(), "api.token.sessions")
+	defer span.End()
+
+	span.SetAttributes(attribute.Int("version", 1))
+
+	accessToken, err := api.service.Parse(ctx, ctx.Value(AccessTokenFieldName).(string))
+	if err != nil {
+		http.Error(writer, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		api.logger.Error(err)
+		return
+	}
+
+	sessions, err := api.service.Sessions(ctx, accessToken.Login)
+	if err != nil {
+		api.serviceError(writer, err)
+		return
+	}
+
+	models := make([]*Session, 0, len(sessions))
+	for _, session := range sessions {
+		models = append(models, &Session{
+			UUID:         session.UUID,
+			Ip:           session.Ip,
+			UserAgent:    session.UserAgent,
+			CreatedAt:    session.CreatedAt,
+			ExpiresIn:    session.ExpiresIn,
+			RevokedAt:    session.RevokedAt,
+			RevokeReason: session.RevokeReason,
+		})
+	}
+
+	body, err := json.Marshal(models)
+	if err != nil {
 		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		api.logger.Error(err)
 		return
 	}
 
-	writer.WriteHeader(http.StatusAccepted)
+	writer.Header().Add(headers.ContentType, mimetype.ApplicationJSON)
+	writer.WriteHeader(http.StatusOK)
+
+	if _, err := writer.Write(body); err != nil {
+		api.logger.Error(err)
+	}
+}
+
+// setCookie writing cookie of refresh token with configured attributes
+func (api *api) setCookie(writer http.ResponseWriter, value string, expires time.Time) {
+	cookie := api.cookie.Cookie(value)
+	cookie.Expires = expires
+
+	http.SetCookie(writer, cookie)
+}
+
+// clearCookie removing cookie of refresh token, browser removes cookie only with the same name, path and domain
+func (api *api) clearCookie(writer http.ResponseWriter) {
+	cookie := api.cookie.Cookie("")
+	cookie.Expires = time.Unix(0, 0)
+	cookie.MaxAge = -1
+
+	http.SetCookie(writer, cookie)
+}
+
+// serviceError writing status of error from service, full buffers of service return 503 so clients can retry later
+func (api *api) serviceError(writer http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if err == application.BufferFullError {
+		status = http.StatusServiceUnavailable
+	}
+
+	http.Error(writer, http.StatusText(status), status)
+	api.logger.Error(err)
 }
@@ -0,0 +1,130 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"github.com/Diez37/go-skeleton/application"
+	"github.com/Diez37/go-skeleton/domain"
+	"github.com/Diez37/go-skeleton/infrastructure/config"
+	"github.com/Diez37/go-skeleton/infrastructure/keyring"
+	"github.com/Diez37/go-skeleton/infrastructure/metrics"
+	"github.com/Diez37/go-skeleton/infrastructure/repository"
+	"github.com/diez37/go-packages/app"
+	"github.com/diez37/go-packages/log"
+	"github.com/go-http-utils/headers"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testMetrics = metrics.NewMetrics(&app.Config{Name: "test"})
+
+var testTracer = trace.NewNoopTracerProvider().Tracer("")
+
+func testLogger() log.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return logger
+}
+
+// newTestService creating Token over memory repository with keyring of secret
+func newTestService(tokenConfig *config.Token, secret string) application.Token {
+	tokenRepository := repository.NewMemory(testTracer)
+
+	return application.NewToken(
+		application.NewPolicy(tokenConfig),
+		testLogger(),
+		tokenRepository,
+		tokenRepository,
+		tokenRepository,
+		application.NewEvents(nil, nil, testTracer),
+		keyring.NewSecret(secret),
+		testMetrics,
+		testTracer,
+	)
+}
+
+func TestSessionsDeniesForeignToken(t *testing.T) {
+	ctx := context.Background()
+
+	tokenConfig := &config.Token{
+		MaximumTokens:      config.TokensMaximumTokensDefault,
+		AccessLifetime:     config.TokensAccessLifetimeDefault,
+		RefreshLifetime:    config.TokensRefreshLifetimeDefault,
+		AccessViolation:    config.TokensAccessViolationActionDefault,
+		RefreshCheckFields: config.TokensCheckFieldsForRefresh,
+	}
+
+	service := newTestService(tokenConfig, "0123456789abcdef0123456789abcdef")
+	router := Router(testLogger(), config.NewCookie(), service, validator.New(), testTracer)
+
+	session := &domain.RefreshToken{Login: uuid.New(), Ip: net.ParseIP("127.0.0.1"), UserAgent: "test"}
+
+	_, accessToken, err := service.Create(ctx, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the token of the same login signed by other secret is forged
+	_, forged, err := newTestService(tokenConfig, "fedcba9876543210fedcba9876543210").Create(ctx, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for token, status := range map[string]int{accessToken: http.StatusOK, forged: http.StatusForbidden} {
+		request := httptest.NewRequest(http.MethodGet, "/v1/sessions", nil)
+		request.Header.Set(headers.Authorization, BearerAuthorizationType+" "+token)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != status {
+			t.Fatalf("sessions by token '%s', status %d, want %d", token, recorder.Code, status)
+		}
+	}
+}
+
+func TestCreateSetsCookieUntilExpiryOfToken(t *testing.T) {
+	tokenConfig := &config.Token{
+		MaximumTokens:      config.TokensMaximumTokensDefault,
+		AccessLifetime:     config.TokensAccessLifetimeDefault,
+		RefreshLifetime:    time.Hour,
+		AccessViolation:    config.TokensAccessViolationActionDefault,
+		RefreshCheckFields: config.TokensCheckFieldsForRefresh,
+	}
+
+	cookie := &config.Cookie{Name: config.CookieNameDefault, Path: config.CookiePathDefault}
+
+	router := Router(testLogger(), cookie, newTestService(tokenConfig, "0123456789abcdef0123456789abcdef"), validator.New(), testTracer)
+
+	request := httptest.NewRequest(http.MethodPut, "/v1/", strings.NewReader(fmt.Sprintf(`{"login":"%s","fingerprint":"fingerprint"}`, uuid.New())))
+	request.Header.Set(headers.UserAgent, "test")
+
+	before := time.Now().In(time.UTC).Truncate(time.Second)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("create, status %d, want %d", recorder.Code, http.StatusOK)
+	}
+
+	cookies := recorder.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("%d cookies, want cookie of refresh token", len(cookies))
+	}
+
+	// the cookie expires together with refresh token by lifetime of policy
+	if expires := cookies[0].Expires; expires.Before(before.Add(time.Hour)) || expires.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("cookie expires at %s, want after lifetime %s", expires, time.Hour)
+	}
+}
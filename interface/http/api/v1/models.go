@@ -14,6 +14,17 @@ type RefreshToken struct {
 	Fingerprint string `json:"fingerprint" validate:"required"`
 }
 
+// Session refresh token of login, revoke_reason is set for revoked session
+type Session struct {
+	UUID         uuid.UUID  `json:"uuid"`
+	Ip           string     `json:"ip"`
+	UserAgent    string     `json:"user_agent"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresIn    time.Time  `json:"expires_in"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason string     `json:"revoke_reason,omitempty"`
+}
+
 type AccessToken struct {
 	Login     uuid.UUID `json:"login"`
 	ExpiresIn time.Time `json:"expires_in"`
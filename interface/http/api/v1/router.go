@@ -13,14 +13,14 @@ import (
 
 func Router(
 	logger log.Logger,
-	config *config.Token,
+	cookie *config.Cookie,
 	service application.Token,
 	validator *validator.Validate,
 	tracer trace.Tracer,
 ) chi.Router {
 	router := chi.NewRouter()
 
-	api := NewApi(config, logger, service, validator, tracer)
+	api := NewApi(cookie, logger, service, validator, tracer)
 
 	ipMiddleware := middlewares.NewIP(middlewares.IpWithName(IpFieldName)).Middleware
 	userAgentMiddleware := middlewares.NewString(
@@ -30,7 +30,7 @@ func Router(
 	).Middleware
 	refreshTokenMiddleware := middlewares.NewUUID(
 		logger,
-		middlewares.WithCookie(RefreshTokenFieldName),
+		middlewares.WithCookie(cookie.FullName()),
 		middlewares.WithName(RefreshTokenFieldName),
 	).Middleware
 
@@ -45,6 +45,7 @@ func Router(
 			r.Use(BearerAuthorization)
 			r.Get("/", api.Read)
 			r.Options("/", api.Validation)
+			r.Get("/sessions", api.Sessions)
 
 			r.Group(func(r chi.Router) {
 				r.Use(refreshTokenMiddleware)
@@ -0,0 +1,27 @@
+package http
+
+import (
+	"github.com/Diez37/go-skeleton/application"
+	"github.com/diez37/go-packages/log"
+	"net/http"
+)
+
+// liveness responding while process serves requests
+func liveness(writer http.ResponseWriter, _ *http.Request) {
+	writer.WriteHeader(http.StatusOK)
+	_, _ = writer.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+// readiness responding 503 with the failed check while service is not ready
+func readiness(health application.Health, logger log.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if err := health.Ready(request.Context()); err != nil {
+			logger.Warnf("http server: not ready - %s", err)
+			http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		writer.WriteHeader(http.StatusOK)
+		_, _ = writer.Write([]byte(http.StatusText(http.StatusOK)))
+	}
+}
@@ -14,24 +14,42 @@ import (
 	"golang.org/x/sync/errgroup"
 	"net"
 	"net/http"
+	"time"
 )
 
-// Serve configuration and running http server
-func Serve(ctx context.Context, container container.Container, logger log.Logger, service application.Token, tracer trace.Tracer) error {
+// Serve configuration and running http server. On shutdown the service becomes not ready and after the pause
+// of drain the server waits for in-flight requests up to timeout of shutdown, Serve returns after the requests are finished
+func Serve(
+	ctx context.Context,
+	container container.Container,
+	logger log.Logger,
+	service application.Token,
+	health application.Health,
+	tracer trace.Tracer,
+) error {
 	ctx, cancelFunc := context.WithCancel(ctx)
 	defer cancelFunc()
 
+	// requests are not canceled by ctx, so that they finish their writes while server drains
+	requestsCtx, requestsCancelFunc := context.WithCancel(context.Background())
+	defer requestsCancelFunc()
+
 	errGroup := &errgroup.Group{}
 
 	err := container.Invoke(func(
 		server *http.Server,
 		config *httpServer.Config,
 		tokenConfig *config.Token,
+		cookieConfig *config.Cookie,
 		validator *validator.Validate,
 		router chi.Router,
 	) {
 		logger.Info("http server: add '/token' handler")
-		router.Mount("/token", api.Router(logger, tokenConfig, service, validator, tracer))
+		router.Mount("/token", api.Router(logger, cookieConfig, service, validator, tracer))
+
+		logger.Info("http server: add '/healthz' and '/readyz' handlers")
+		router.Get("/healthz", liveness)
+		router.Get("/readyz", readiness(health, logger))
 
 		errGroup.Go(func() error {
 			defer cancelFunc()
@@ -39,7 +57,7 @@ func Serve(ctx context.Context, container container.Container, logger log.Logger
 			logger.Infof("http server: started")
 
 			server.BaseContext = func(_ net.Listener) context.Context {
-				return ctx
+				return requestsCtx
 			}
 
 			if err := server.ListenAndServe(); err != http.ErrServerClosed {
@@ -52,9 +70,29 @@ func Serve(ctx context.Context, container container.Container, logger log.Logger
 		errGroup.Go(func() error {
 			<-ctx.Done()
 
-			logger.Infof("http server: shutdown")
+			health.Stop()
+
+			// the server accepts requests while balancers notice that the service is not ready
+			if tokenConfig.ReadyDrain > 0 {
+				logger.Infof("http server: not ready, shutdown after %s", tokenConfig.ReadyDrain)
+
+				time.Sleep(tokenConfig.ReadyDrain)
+			}
+
+			logger.Infof("http server: shutdown, timeout - %s", config.ShutdownTimeout)
 
-			return server.Close()
+			shutdownCtx, shutdownCancelFunc := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+			defer shutdownCancelFunc()
+
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				logger.Warnf("http server: requests not finished on shutdown, closed - %s", err)
+
+				requestsCancelFunc()
+
+				return server.Close()
+			}
+
+			return nil
 		})
 	})
 
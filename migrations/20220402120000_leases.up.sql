@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS leases
+(
+    name       VARCHAR(64) NOT NULL PRIMARY KEY,
+    holder     CHAR(36)    NOT NULL,
+    expires_in TIMESTAMP   NOT NULL
+    );
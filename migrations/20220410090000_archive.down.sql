@@ -0,0 +1,25 @@
+DROP TABLE IF EXISTS refresh_tokens_archive;
+
+-- the table is rebuilt without columns of revoke, so that its indexes are dropped with it on every driver,
+-- the revoked tokens were deleted before archive
+CREATE TABLE IF NOT EXISTS refresh_tokens_previous
+(
+    uuid        CHAR(36)     NOT NULL PRIMARY KEY,
+    login       CHAR(36)     NOT NULL,
+    ip          VARCHAR(45)  NOT NULL,
+    fingerprint VARCHAR(256) NOT NULL,
+    user_agent  VARCHAR(256) NOT NULL,
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_in  TIMESTAMP    NOT NULL
+    );
+
+INSERT INTO refresh_tokens_previous (uuid, login, ip, fingerprint, user_agent, created_at, expires_in)
+SELECT uuid, login, ip, fingerprint, user_agent, created_at, expires_in
+FROM refresh_tokens
+WHERE revoked_at IS NULL;
+
+DROP TABLE refresh_tokens;
+
+ALTER TABLE refresh_tokens_previous RENAME TO refresh_tokens;
+
+CREATE UNIQUE INDEX refresh_tokens_login_uuid ON refresh_tokens (login, uuid);
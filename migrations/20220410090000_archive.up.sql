@@ -0,0 +1,21 @@
+ALTER TABLE refresh_tokens ADD COLUMN revoked_at TIMESTAMP NULL;
+ALTER TABLE refresh_tokens ADD COLUMN revoke_reason VARCHAR(32) NOT NULL DEFAULT '';
+
+CREATE INDEX refresh_tokens_revoked_at ON refresh_tokens (revoked_at);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens_archive
+(
+    uuid          CHAR(36)     NOT NULL PRIMARY KEY,
+    login         CHAR(36)     NOT NULL,
+    ip            VARCHAR(45)  NOT NULL,
+    fingerprint   VARCHAR(256) NOT NULL,
+    user_agent    VARCHAR(256) NOT NULL,
+    created_at    TIMESTAMP    NOT NULL,
+    expires_in    TIMESTAMP    NOT NULL,
+    revoked_at    TIMESTAMP    NOT NULL,
+    revoke_reason VARCHAR(32)  NOT NULL,
+    archived_at   TIMESTAMP    NOT NULL
+    );
+
+CREATE INDEX refresh_tokens_archive_archived_at ON refresh_tokens_archive (archived_at);
+CREATE INDEX refresh_tokens_archive_login ON refresh_tokens_archive (login);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS events_outbox
+(
+    uuid        CHAR(36)     NOT NULL PRIMARY KEY,
+    destination VARCHAR(256) NOT NULL,
+    type        VARCHAR(64)  NOT NULL,
+    payload     TEXT         NOT NULL,
+    attempts    INT          NOT NULL DEFAULT 0,
+    created_at  TIMESTAMP    NOT NULL,
+    send_at     TIMESTAMP    NOT NULL
+    );
+
+CREATE INDEX events_outbox_send_at ON events_outbox (send_at);